@@ -0,0 +1,113 @@
+package sn76489
+
+import "math"
+
+// ChannelInfo is a musical description of what a channel is currently
+// playing, as returned by AnalyzeChannel.
+type ChannelInfo struct {
+	Channel int    // 0-2 = tone channels, 3 = noise
+	Period  uint16 // Effective counter period in internal ticks (tone reg 0 per ToneZero)
+
+	// Frequency is the output fundamental in Hz. For tone channels it is
+	// the square wave frequency; for periodic noise it is the repeat rate of
+	// the LFSR pattern; for white noise it is the LFSR shift rate. Zero
+	// when Constant.
+	Frequency float64
+
+	// Constant is true for a tone channel whose effective period is 1: the
+	// output is held high (used for PCM sample playback) and has no pitch.
+	Constant bool
+
+	Note  int     // Nearest MIDI note (69 = A4 at 440 Hz); -1 when Constant
+	Cents float64 // Deviation from Note in cents (-50 to +50)
+
+	Volume      uint8   // 4-bit volume register (0 = max, 15 = off)
+	Attenuation float64 // Effective attenuation in dB; +Inf when off
+
+	// Noise channel only.
+	White        bool  // White noise (false = periodic)
+	NoiseRate    uint8 // Shift rate bits (0-2 fixed, 3 = follows tone 2)
+	FollowsTone2 bool  // NoiseRate is 3; Period and Frequency track tone channel 2
+}
+
+// Muted reports whether the channel's volume is off.
+func (c ChannelInfo) Muted() bool {
+	return c.Volume == 0x0F
+}
+
+// AnalyzeChannel returns the current frequency, nearest MIDI note, cent
+// deviation and attenuation of channel ch (0-2 tone, 3 noise), derived from
+// the registers and the chip's clock frequency.
+func (s *SN76489) AnalyzeChannel(ch int) ChannelInfo {
+	info := ChannelInfo{
+		Channel:     ch,
+		Volume:      s.volume[ch],
+		Attenuation: attenuationDB(s.volume[ch]),
+	}
+
+	// Counters tick at clock/16; tone outputs toggle once per period, and
+	// the LFSR shifts on every second noise counter reload.
+	tick := float64(s.clockFreq) / 16
+
+	if ch < 3 {
+		info.Period = s.effectiveTonePeriod(ch)
+		if info.Period <= 1 {
+			info.Constant = true
+			info.Note = -1
+			return info
+		}
+		info.Frequency = tick / (2 * float64(info.Period))
+	} else {
+		info.White = s.noiseReg&0x04 != 0
+		info.NoiseRate = s.noiseReg & 0x03
+		info.FollowsTone2 = info.NoiseRate == 3
+		if info.FollowsTone2 {
+			info.Period = s.effectiveTonePeriod(2)
+		} else {
+			info.Period = 0x10 << info.NoiseRate
+		}
+		info.Frequency = tick / (2 * float64(info.Period))
+		if !info.White {
+			info.Frequency /= float64(s.feedbackShift + 1)
+		}
+	}
+
+	info.Note, info.Cents = midiNote(info.Frequency)
+	return info
+}
+
+// Analyze returns AnalyzeChannel for all four channels.
+func (s *SN76489) Analyze() [4]ChannelInfo {
+	var infos [4]ChannelInfo
+	for ch := range infos {
+		infos[ch] = s.AnalyzeChannel(ch)
+	}
+	return infos
+}
+
+// effectiveTonePeriod returns tone channel ch's counter period, with
+// register value 0 mapped per ToneZero.
+func (s *SN76489) effectiveTonePeriod(ch int) uint16 {
+	if s.toneReg[ch] == 0 {
+		return s.toneZeroValue
+	}
+	return s.toneReg[ch]
+}
+
+// midiNote returns the nearest MIDI note to freq and the deviation from it
+// in cents.
+func midiNote(freq float64) (note int, cents float64) {
+	exact := 69 + 12*math.Log2(freq/440)
+	note = int(math.Round(exact))
+	return note, (exact - float64(note)) * 100
+}
+
+// attenuationDB returns the attenuation of a 4-bit volume level in dB,
+// derived from the volume table.
+func attenuationDB(level uint8) float64 {
+	amp := float64(volumeTable[level])
+	if amp == 0 {
+		return math.Inf(1)
+	}
+	return -20 * math.Log10(amp)
+}
@@ -0,0 +1,131 @@
+package sn76489
+
+import (
+	"math"
+	"testing"
+)
+
+// writeTone sets tone channel ch to a 10-bit register value.
+func writeTone(chip *SN76489, ch int, reg uint16) {
+	b := EncodeTone(ch, reg)
+	chip.Write(b[0])
+	chip.Write(b[1])
+}
+
+// TestSN76489_AnalyzeTone verifies frequency, note and cents for a tone
+// channel. Register 254 at the NTSC clock is A4 (440.4 Hz).
+func TestSN76489_AnalyzeTone(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	writeTone(chip, 1, 254)
+	chip.Write(0xB3) // Channel 1 volume = 3
+
+	info := chip.AnalyzeChannel(1)
+	wantFreq := 3579545.0 / (32 * 254)
+	if math.Abs(info.Frequency-wantFreq) > 1e-9 {
+		t.Errorf("Frequency: expected %f, got %f", wantFreq, info.Frequency)
+	}
+	if info.Note != 69 {
+		t.Errorf("Note: expected 69, got %d", info.Note)
+	}
+	wantCents := 1200 * math.Log2(wantFreq/440)
+	if math.Abs(info.Cents-wantCents) > 1e-9 || info.Cents <= 0 {
+		t.Errorf("Cents: expected %f, got %f", wantCents, info.Cents)
+	}
+	if info.Constant || info.Period != 254 {
+		t.Errorf("Period: expected 254, got %d (constant=%v)", info.Period, info.Constant)
+	}
+	if math.Abs(info.Attenuation-6) > 1e-4 || info.Muted() {
+		t.Errorf("Attenuation: expected 6 dB, got %f", info.Attenuation)
+	}
+
+	if off := chip.AnalyzeChannel(0); !math.IsInf(off.Attenuation, 1) || !off.Muted() {
+		t.Errorf("Silent channel: expected +Inf dB, got %f", off.Attenuation)
+	}
+}
+
+// TestSN76489_AnalyzeToneZeroAndOne verifies register 0 follows ToneZero and
+// register 1 is a constant output.
+func TestSN76489_AnalyzeToneZeroAndOne(t *testing.T) {
+	sega := New(3579545, 48000, 800, Sega)
+	ti := New(3579545, 48000, 800, TI)
+
+	if info := sega.AnalyzeChannel(0); !info.Constant || info.Note != -1 || info.Frequency != 0 {
+		t.Errorf("Sega reg 0: expected constant, got %+v", info)
+	}
+	info := ti.AnalyzeChannel(0)
+	if info.Constant || info.Period != 1024 {
+		t.Errorf("TI reg 0: expected period 1024, got %+v", info)
+	}
+	if want := 3579545.0 / (32 * 1024); math.Abs(info.Frequency-want) > 1e-9 {
+		t.Errorf("TI reg 0: expected %f Hz, got %f", want, info.Frequency)
+	}
+
+	for _, chip := range []*SN76489{sega, ti} {
+		writeTone(chip, 2, 1)
+		if info := chip.AnalyzeChannel(2); !info.Constant || info.Period != 1 {
+			t.Errorf("Reg 1: expected constant, got %+v", info)
+		}
+	}
+}
+
+// TestSN76489_AnalyzeNoise verifies fixed noise rates, periodic pattern
+// length and rate 3 following tone channel 2.
+func TestSN76489_AnalyzeNoise(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	tick := 3579545.0 / 16
+
+	chip.Write(0xE5) // White, rate 1
+	info := chip.AnalyzeChannel(3)
+	if !info.White || info.NoiseRate != 1 || info.Period != 0x20 || info.FollowsTone2 {
+		t.Errorf("White rate 1: got %+v", info)
+	}
+	if want := tick / (2 * 0x20); math.Abs(info.Frequency-want) > 1e-9 {
+		t.Errorf("White rate 1: expected %f Hz, got %f", want, info.Frequency)
+	}
+
+	chip.Write(0xE3) // Periodic, rate 3
+	writeTone(chip, 2, 100)
+	info = chip.AnalyzeChannel(3)
+	if info.White || !info.FollowsTone2 || info.Period != 100 {
+		t.Errorf("Periodic rate 3: got %+v", info)
+	}
+	if want := tick / (2 * 100 * 16); math.Abs(info.Frequency-want) > 1e-9 {
+		t.Errorf("Periodic rate 3 (16-bit LFSR): expected %f Hz, got %f", want, info.Frequency)
+	}
+
+	// Tone 2 register 0 maps per ToneZero for the noise too.
+	ti := New(3579545, 48000, 800, TI)
+	ti.Write(0xE3)
+	info = ti.AnalyzeChannel(3)
+	if info.Period != 1024 {
+		t.Errorf("TI rate 3 with tone 2 reg 0: expected period 1024, got %d", info.Period)
+	}
+	if want := tick / (2 * 1024 * 15); math.Abs(info.Frequency-want) > 1e-9 {
+		t.Errorf("TI periodic (15-bit LFSR): expected %f Hz, got %f", want, info.Frequency)
+	}
+
+	if all := chip.Analyze(); all[3] != chip.AnalyzeChannel(3) || all[2].Period != 100 {
+		t.Error("Analyze should match AnalyzeChannel")
+	}
+}
+
+// TestSN76489_AnalyzeMatchesOutput verifies the reported frequency matches
+// the number of rising edges in generated output.
+func TestSN76489_AnalyzeMatchesOutput(t *testing.T) {
+	chip := New(3579545, 48000, 48000, Sega)
+	writeTone(chip, 0, 0x1AC)
+	chip.Write(0x90)
+	want := chip.AnalyzeChannel(0).Frequency
+
+	chip.GenerateSamples(3579545)
+	bufs, n := chip.GetChannelBuffers()
+	edges := 0
+	for i := 1; i < n; i++ {
+		if bufs[0][i] > 0 && bufs[0][i-1] == 0 {
+			edges++
+		}
+	}
+	if math.Abs(float64(edges)-want) > 1 {
+		t.Errorf("Rising edges in 1 s: expected ~%f, got %d", want, edges)
+	}
+}
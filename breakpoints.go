@@ -0,0 +1,173 @@
+package sn76489
+
+// BreakpointHit describes a breakpoint that fired.
+type BreakpointHit struct {
+	ID    int         // ID returned when the breakpoint was added
+	Clock uint64      // Input clock position (see GetClock)
+	Write *WriteTrace // The write that fired it; nil for a state breakpoint during Run
+}
+
+// breakpoint is a registered write or state condition.
+type breakpoint struct {
+	id      int
+	onWrite func(WriteTrace) bool
+	onState func(*SN76489) bool
+	last    bool // Last onState result, for edge detection
+	removed bool // Removed by a break handler; dropped after the check
+}
+
+// AddWriteBreakpoint adds a condition evaluated on every decoded Write. The
+// breakpoint fires each time cond returns true. Returns the breakpoint ID.
+//
+//	// Break when channel 3 switches to white noise.
+//	chip.AddWriteBreakpoint(func(w sn76489.WriteTrace) bool {
+//		return w.Channel == 3 && !w.Volume && w.Register&0x04 != 0
+//	})
+func (s *SN76489) AddWriteBreakpoint(cond func(WriteTrace) bool) int {
+	s.nextBreakID++
+	s.breakpoints = append(s.breakpoints, breakpoint{id: s.nextBreakID, onWrite: cond})
+	return s.nextBreakID
+}
+
+// AddStateBreakpoint adds a condition on the chip state, evaluated after
+// every Write and after every internal tick (16 input clocks) during Run.
+// The breakpoint fires when cond changes from false to true, so a condition
+// that stays true fires once. cond must only read the chip. Returns the
+// breakpoint ID.
+//
+//	// Break when the tone 2 register drops below 0x010.
+//	chip.AddStateBreakpoint(func(c *sn76489.SN76489) bool {
+//		return c.GetToneReg(2) < 0x010
+//	})
+func (s *SN76489) AddStateBreakpoint(cond func(*SN76489) bool) int {
+	s.nextBreakID++
+	s.breakpoints = append(s.breakpoints, breakpoint{id: s.nextBreakID, onState: cond, last: cond(s)})
+	return s.nextBreakID
+}
+
+// RemoveBreakpoint removes the breakpoint with the given ID. It may be
+// called from the break handler, e.g. for a one-shot breakpoint.
+func (s *SN76489) RemoveBreakpoint(id int) {
+	for i, bp := range s.breakpoints {
+		if bp.id == id {
+			if s.breakDepth > 0 {
+				s.breakpoints[i].removed = true
+				s.breakRemoved = true
+				return
+			}
+			s.breakpoints = append(s.breakpoints[:i:i], s.breakpoints[i+1:]...)
+			return
+		}
+	}
+}
+
+// ClearBreakpoints removes all breakpoints. It may be called from the break
+// handler.
+func (s *SN76489) ClearBreakpoints() {
+	if s.breakDepth > 0 {
+		for i := range s.breakpoints {
+			s.breakpoints[i].removed = true
+		}
+		s.breakRemoved = true
+		return
+	}
+	s.breakpoints = nil
+}
+
+// SetBreakHandler sets a callback run synchronously each time a breakpoint
+// fires. nil removes it.
+func (s *SN76489) SetBreakHandler(fn func(BreakpointHit)) {
+	s.breakHandler = fn
+}
+
+// PendingBreak returns the first breakpoint hit since the last ClearBreak.
+// The chip does not stop by itself: a Run in progress completes, so a
+// debugger checks PendingBreak after each Write or Run and pauses emulation
+// when ok is true. hit.Clock says exactly where the break occurred.
+func (s *SN76489) PendingBreak() (hit BreakpointHit, ok bool) {
+	return s.pendingBreak, s.breakPending
+}
+
+// ClearBreak clears the pending break, for resuming emulation.
+func (s *SN76489) ClearBreak() {
+	s.pendingBreak = BreakpointHit{}
+	s.breakPending = false
+}
+
+// checkWriteBreakpoints evaluates all breakpoints after a write.
+//
+// Break handlers may add or remove breakpoints, or write to the chip. The
+// loop only visits breakpoints that existed when it started, indexes the
+// slice afresh each time since an add may reallocate it, and removals are
+// deferred until the outermost check returns.
+func (s *SN76489) checkWriteBreakpoints(w WriteTrace) {
+	s.breakDepth++
+	for i, n := 0, len(s.breakpoints); i < n; i++ {
+		bp := &s.breakpoints[i]
+		switch {
+		case bp.removed:
+		case bp.onWrite != nil:
+			if bp.onWrite(w) {
+				s.hitBreakpoint(bp.id, &w)
+			}
+		default:
+			s.checkStateBreakpoint(i, &w)
+		}
+	}
+	s.endBreakCheck()
+}
+
+// checkStateBreakpoints evaluates the state breakpoints after an internal
+// tick.
+func (s *SN76489) checkStateBreakpoints() {
+	s.breakDepth++
+	for i, n := 0, len(s.breakpoints); i < n; i++ {
+		if bp := &s.breakpoints[i]; bp.onState != nil && !bp.removed {
+			s.checkStateBreakpoint(i, nil)
+		}
+	}
+	s.endBreakCheck()
+}
+
+// checkStateBreakpoint fires breakpoint i if its condition has become true.
+func (s *SN76489) checkStateBreakpoint(i int, w *WriteTrace) {
+	bp := &s.breakpoints[i]
+	now := bp.onState(s)
+	was := bp.last
+	bp.last = now
+	if now && !was {
+		s.hitBreakpoint(bp.id, w)
+	}
+}
+
+// endBreakCheck drops breakpoints removed during the outermost check.
+func (s *SN76489) endBreakCheck() {
+	s.breakDepth--
+	if s.breakDepth > 0 || !s.breakRemoved {
+		return
+	}
+	s.breakRemoved = false
+	var kept []breakpoint
+	for _, bp := range s.breakpoints {
+		if !bp.removed {
+			kept = append(kept, bp)
+		}
+	}
+	s.breakpoints = kept
+}
+
+// hitBreakpoint records a hit and runs the break handler.
+func (s *SN76489) hitBreakpoint(id int, w *WriteTrace) {
+	hit := BreakpointHit{ID: id, Clock: s.clocks}
+	if w != nil {
+		copied := *w
+		hit.Write = &copied
+	}
+	if !s.breakPending {
+		s.pendingBreak = hit
+		s.breakPending = true
+	}
+	if s.breakHandler != nil {
+		s.breakHandler(hit)
+	}
+}
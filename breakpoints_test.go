@@ -0,0 +1,171 @@
+package sn76489
+
+import "testing"
+
+// TestSN76489_WriteBreakpoint verifies a write condition fires on each
+// matching write with the decoded write attached.
+func TestSN76489_WriteBreakpoint(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	var hits []BreakpointHit
+	chip.SetBreakHandler(func(h BreakpointHit) { hits = append(hits, h) })
+	id := chip.AddWriteBreakpoint(func(w WriteTrace) bool {
+		return w.Channel == 3 && !w.Volume && w.Register&0x04 != 0
+	})
+
+	chip.Write(0xE0) // Periodic: no hit
+	chip.Run(100)
+	chip.Write(0xE5) // White: hit
+	chip.Write(0xF0) // Noise volume: no hit
+	chip.Write(0xE4) // White again: hit
+
+	if len(hits) != 2 {
+		t.Fatalf("Expected 2 hits, got %d", len(hits))
+	}
+	if hits[0].ID != id || hits[0].Clock != 100 || hits[0].Write == nil || hits[0].Write.Value != 0xE5 {
+		t.Errorf("First hit: %+v", hits[0])
+	}
+	if hits[1].Write.Value != 0xE4 {
+		t.Errorf("Second hit: expected write 0xE4, got 0x%02X", hits[1].Write.Value)
+	}
+
+	// The pending break is the first hit until cleared.
+	hit, ok := chip.PendingBreak()
+	if !ok || hit.Write.Value != 0xE5 {
+		t.Errorf("PendingBreak: ok=%v hit=%+v", ok, hit)
+	}
+	chip.ClearBreak()
+	if _, ok := chip.PendingBreak(); ok {
+		t.Error("PendingBreak should be clear after ClearBreak")
+	}
+}
+
+// TestSN76489_StateBreakpointOnWrite verifies a state condition fires once
+// when it becomes true through a write, and again only after it has been
+// false.
+func TestSN76489_StateBreakpointOnWrite(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	writeTone(chip, 2, 0x100)
+	count := 0
+	chip.SetBreakHandler(func(BreakpointHit) { count++ })
+	chip.AddStateBreakpoint(func(c *SN76489) bool {
+		return c.GetToneReg(2) < 0x010
+	})
+
+	writeTone(chip, 2, 0x00F) // Drops below: hit
+	writeTone(chip, 2, 0x008) // Still below: no hit
+	chip.Run(1000)            // Still below: no hit
+	writeTone(chip, 2, 0x200) // Rises above
+	writeTone(chip, 2, 0x001) // Drops below again: hit
+	if count != 2 {
+		t.Errorf("Expected 2 hits, got %d", count)
+	}
+}
+
+// TestSN76489_StateBreakpointDuringRun verifies state conditions are checked
+// on internal ticks, with the exact clock of the transition.
+func TestSN76489_StateBreakpointDuringRun(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	writeTone(chip, 0, 10)
+	chip.Write(0x90)
+
+	id := chip.AddStateBreakpoint(func(c *SN76489) bool {
+		return c.GetToneOutput(0)
+	})
+	var hits []uint64
+	chip.SetBreakHandler(func(h BreakpointHit) { hits = append(hits, h.Clock) })
+	chip.Run(1000)
+
+	hit, ok := chip.PendingBreak()
+	if !ok || hit.ID != id || hit.Write != nil {
+		t.Fatalf("Expected a state hit, got ok=%v %+v", ok, hit)
+	}
+	if chip.GetClock() != 1000 {
+		t.Errorf("Run should complete: clock %d", chip.GetClock())
+	}
+
+	// The counter starts at 0, so the first internal tick (16 clocks)
+	// reloads it and toggles the output high. Later rising edges are a full
+	// period (2 x 10 ticks = 320 clocks) apart.
+	want := []uint64{16, 336, 656, 976}
+	if len(hits) != len(want) {
+		t.Fatalf("Expected hits at %v, got %v", want, hits)
+	}
+	for i := range want {
+		if hits[i] != want[i] {
+			t.Errorf("Hit %d: expected clock %d, got %d", i, want[i], hits[i])
+		}
+	}
+	if hit.Clock != 16 {
+		t.Errorf("PendingBreak should keep the first hit, got clock %d", hit.Clock)
+	}
+}
+
+// TestSN76489_RemoveBreakpoints verifies removal and that breakpoints do
+// not change chip behavior.
+func TestSN76489_RemoveBreakpoints(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	plain := New(3579545, 48000, 800, Sega)
+	count := 0
+	chip.SetBreakHandler(func(BreakpointHit) { count++ })
+	a := chip.AddWriteBreakpoint(func(WriteTrace) bool { return true })
+	chip.AddStateBreakpoint(func(c *SN76489) bool { return c.GetToneOutput(1) })
+
+	chip.RemoveBreakpoint(a)
+	chip.Write(0x90)
+	plain.Write(0x90)
+	if count != 0 {
+		t.Errorf("Removed breakpoint fired: %d hits", count)
+	}
+
+	chip.ClearBreakpoints()
+	writeTone(chip, 1, 5)
+	writeTone(plain, 1, 5)
+	chip.Run(1000)
+	plain.Run(1000)
+	if count != 0 {
+		t.Errorf("Cleared breakpoint fired: %d hits", count)
+	}
+	if chip.SaveState() != plain.SaveState() {
+		t.Error("Breakpoints changed chip state")
+	}
+}
+
+// TestSN76489_RemoveBreakpointFromHandler verifies a break handler can
+// remove breakpoints, for one-shot breakpoints, during Write and Run.
+func TestSN76489_RemoveBreakpointFromHandler(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	var hits []int
+	chip.SetBreakHandler(func(h BreakpointHit) {
+		hits = append(hits, h.ID)
+		chip.RemoveBreakpoint(h.ID)
+	})
+	a := chip.AddWriteBreakpoint(func(WriteTrace) bool { return true })
+	b := chip.AddWriteBreakpoint(func(WriteTrace) bool { return true })
+
+	chip.Write(0x90)
+	chip.Write(0x91)
+	if len(hits) != 2 || hits[0] != a || hits[1] != b {
+		t.Errorf("Write: expected one hit each for %d and %d, got %v", a, b, hits)
+	}
+
+	hits = nil
+	writeTone(chip, 0, 10)
+	c := chip.AddStateBreakpoint(func(c *SN76489) bool { return c.GetToneOutput(0) })
+	chip.AddStateBreakpoint(func(c *SN76489) bool { return !c.GetToneOutput(0) })
+	chip.SetBreakHandler(func(h BreakpointHit) {
+		hits = append(hits, h.ID)
+		chip.ClearBreakpoints()
+	})
+	chip.Run(1000)
+	if len(hits) != 1 || hits[0] != c {
+		t.Errorf("Run: expected one hit for %d, got %v", c, hits)
+	}
+
+	// Breakpoints added afterwards still work.
+	hits = nil
+	d := chip.AddWriteBreakpoint(func(WriteTrace) bool { return true })
+	chip.Write(0x92)
+	if len(hits) != 1 || hits[0] != d {
+		t.Errorf("After removal: expected one hit for %d, got %v", d, hits)
+	}
+}
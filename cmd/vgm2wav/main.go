@@ -0,0 +1,436 @@
+// Command vgm2wav renders the SN76489 part of a VGM or VGZ file to a WAV
+// file or raw PCM, with no emulator involved. It is intended as a reference
+// renderer for bug reports.
+//
+// Usage:
+//
+//	vgm2wav [flags] input.vgm
+//
+// The input may be "-" for stdin. Output goes to stdout unless -o is given.
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/user-none/go-chip-sn76489"
+)
+
+// options holds the parsed command-line flags.
+type options struct {
+	output     string
+	format     string
+	bits       int
+	sampleRate int
+	variant    string
+	loops      int
+	fade       float64
+	gain       float64
+	mute       uint
+	filter     string
+	cutoff     float64
+	stems      string
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.output, "o", "-", "output file, - for stdout")
+	flag.StringVar(&opts.format, "format", "wav", "output format: wav or raw")
+	flag.IntVar(&opts.bits, "bits", 16, "sample format: 16 (int16) or 32 (float32)")
+	flag.IntVar(&opts.sampleRate, "rate", 44100, "output sample rate in Hz")
+	flag.StringVar(&opts.variant, "variant", "auto", "chip variant: auto (from VGM header), sega or ti")
+	flag.IntVar(&opts.loops, "loops", 2, "times to play the looped section")
+	flag.Float64Var(&opts.fade, "fade", 8, "fade-out length in seconds after the last loop")
+	flag.Float64Var(&opts.gain, "gain", 0.25, "gain applied to the channel mix")
+	flag.UintVar(&opts.mute, "mute", 0, "channel mute mask: bit 0-2 tone channels, bit 3 noise")
+	flag.StringVar(&opts.filter, "filter", "none", "output filter: none, dc (DC blocker) or lowpass")
+	flag.Float64Var(&opts.cutoff, "cutoff", 12000, "lowpass filter cutoff in Hz")
+	flag.StringVar(&opts.stems, "stems", "", "also write per-channel WAV stems to PREFIX_tone0.wav ... PREFIX_noise.wav, with -gain and the fade applied but not -mute or -filter")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] input.vgm\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0), opts); err != nil {
+		fmt.Fprintln(os.Stderr, "vgm2wav:", err)
+		os.Exit(1)
+	}
+}
+
+// run loads the input, renders it and writes the output.
+func run(input string, opts options) error {
+	f, err := readInput(input)
+	if err != nil {
+		return err
+	}
+	if f.ClockFreq == 0 {
+		return errors.New("file has no SN76489")
+	}
+	if opts.sampleRate < 1 {
+		return errors.New("sample rate must be positive")
+	}
+	if opts.loops < 1 {
+		opts.loops = 1
+	}
+	if opts.fade < 0 {
+		opts.fade = 0
+	}
+
+	var format sn76489.WAVFormat
+	switch opts.bits {
+	case 16:
+		format = sn76489.WAVInt16
+	case 32:
+		format = sn76489.WAVFloat32
+	default:
+		return fmt.Errorf("unsupported -bits %d", opts.bits)
+	}
+	config, err := chipConfig(f, opts.variant)
+	if err != nil {
+		return err
+	}
+	filt, err := newFilter(opts.filter, opts.cutoff, opts.sampleRate)
+	if err != nil {
+		return err
+	}
+
+	out, finish, err := openOutput(opts, format)
+	if err != nil {
+		return err
+	}
+	var stems *sn76489.WAVStems
+	if opts.stems != "" {
+		var closeStems func() error
+		stems, closeStems, err = openStems(opts.stems, opts.sampleRate, format)
+		if err != nil {
+			return err
+		}
+		defer closeStems()
+	}
+
+	if err := render(f, config, opts, filt, out, stems); err != nil {
+		return err
+	}
+	if stems != nil {
+		if err := stems.Close(); err != nil {
+			return err
+		}
+	}
+	return finish()
+}
+
+// readInput reads and parses the VGM/VGZ input, "-" meaning stdin.
+func readInput(path string) (*sn76489.VGMFile, error) {
+	if path == "-" {
+		return sn76489.ReadVGM(os.Stdin)
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	return sn76489.ReadVGM(in)
+}
+
+// chipConfig resolves the -variant flag against the file header.
+func chipConfig(f *sn76489.VGMFile, variant string) (sn76489.Config, error) {
+	switch strings.ToLower(variant) {
+	case "auto":
+		return f.Config(), nil
+	case "sega":
+		return sn76489.Sega, nil
+	case "ti":
+		return sn76489.TI, nil
+	}
+	return sn76489.Config{}, fmt.Errorf("unknown -variant %q", variant)
+}
+
+// render plays the file and writes the mixed, faded and filtered output.
+func render(f *sn76489.VGMFile, config sn76489.Config, opts options, filt filter, out sampleWriter, stems *sn76489.WAVStems) error {
+	// Length in 44.1 kHz VGM samples: intro plus the loop section played
+	// opts.loops times, then the fade runs on into further repeats.
+	length := uint64(f.TotalSamples)
+	fadeLen := 0
+	chunkClocks := f.ClockFreq / 60
+	if f.LoopOffset >= 0 && f.LoopSamples > 0 {
+		length += uint64(opts.loops-1) * uint64(f.LoopSamples)
+		fadeLen = int(opts.fade * float64(opts.sampleRate))
+	}
+	mainLen := int(length * uint64(opts.sampleRate) / 44100)
+	total := mainLen + fadeLen
+
+	bufSize := int(math.Ceil(float64(chunkClocks)*float64(opts.sampleRate)/float64(f.ClockFreq))) + 1
+	chip := sn76489.New(f.ClockFreq, opts.sampleRate, bufSize, config)
+	player := sn76489.NewVGMPlayer(f, chip)
+	player.SetLoops(-1)
+
+	// Without a length in the header, let the player count the loops and
+	// render until the data runs out. The fade, if the file loops, then
+	// starts where the player ended.
+	unbounded := length == 0
+	if unbounded {
+		player.SetLoops(opts.loops - 1)
+		mainLen = math.MaxInt
+		if f.LoopOffset >= 0 {
+			fadeLen = int(opts.fade * float64(opts.sampleRate))
+		}
+	}
+
+	gain := float32(opts.gain)
+	mix := make([]float32, 0, bufSize)
+	var stemBufs [4][]float32
+	if stems != nil {
+		for ch := range stemBufs {
+			stemBufs[ch] = make([]float32, bufSize)
+		}
+	}
+	written := 0
+	for unbounded || written < total {
+		player.GenerateSamples(chunkClocks)
+		bufs, count := chip.GetChannelBuffers()
+		if !unbounded && count > total-written {
+			count = total - written
+		}
+
+		mix = mix[:0]
+		for i := 0; i < count; i++ {
+			env := gain
+			if pos := written + i; pos >= mainLen {
+				env *= 1 - float32(pos-mainLen)/float32(fadeLen)
+			}
+			var sample float32
+			for ch := 0; ch < 4; ch++ {
+				if opts.mute&(1<<ch) == 0 {
+					sample += bufs[ch][i]
+				}
+				if stems != nil {
+					stemBufs[ch][i] = bufs[ch][i] * env
+				}
+			}
+			mix = append(mix, filt.process(sample*env))
+		}
+		if err := out.WriteSamples(mix); err != nil {
+			return err
+		}
+		if stems != nil {
+			for ch := 0; ch < 4; ch++ {
+				if err := stems.Writer(ch).WriteSamples(stemBufs[ch][:count]); err != nil {
+					return err
+				}
+			}
+		}
+		written += count
+
+		if unbounded && player.Ended() {
+			unbounded = false
+			mainLen = written
+			total = written + fadeLen
+		}
+	}
+	return nil
+}
+
+// sampleWriter receives mono output samples.
+type sampleWriter interface {
+	WriteSamples(samples []float32) error
+}
+
+// openOutput creates the output writer and returns a function that
+// finishes and closes it.
+func openOutput(opts options, format sn76489.WAVFormat) (sampleWriter, func() error, error) {
+	var dst io.Writer = os.Stdout
+	closeDst := func() error { return nil }
+	if opts.output != "-" {
+		file, err := os.Create(opts.output)
+		if err != nil {
+			return nil, nil, err
+		}
+		dst = file
+		closeDst = file.Close
+	}
+
+	switch opts.format {
+	case "raw":
+		w := &rawWriter{w: dst, format: format}
+		return w, closeDst, nil
+	case "wav":
+		// Stdout is not seekable, so build the file in memory and copy it
+		// out once the header sizes are known.
+		ws, ok := dst.(io.WriteSeeker)
+		var mem *memWriteSeeker
+		if !ok || opts.output == "-" {
+			mem = &memWriteSeeker{}
+			ws = mem
+		}
+		w, err := sn76489.NewWAVWriter(ws, opts.sampleRate, 1, format)
+		if err != nil {
+			closeDst()
+			return nil, nil, err
+		}
+		finish := func() error {
+			if err := w.Close(); err != nil {
+				closeDst()
+				return err
+			}
+			if mem != nil {
+				if _, err := dst.Write(mem.buf); err != nil {
+					closeDst()
+					return err
+				}
+			}
+			return closeDst()
+		}
+		return w, finish, nil
+	}
+	closeDst()
+	return nil, nil, fmt.Errorf("unknown -format %q", opts.format)
+}
+
+// openStems creates the four stem files and their writers.
+func openStems(prefix string, sampleRate int, format sn76489.WAVFormat) (*sn76489.WAVStems, func() error, error) {
+	names := [4]string{"tone0", "tone1", "tone2", "noise"}
+	var files []*os.File
+	closeAll := func() error {
+		var first error
+		for _, file := range files {
+			if err := file.Close(); err != nil && first == nil {
+				first = err
+			}
+		}
+		return first
+	}
+
+	var ws [4]io.WriteSeeker
+	for ch, name := range names {
+		file, err := os.Create(prefix + "_" + name + ".wav")
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		files = append(files, file)
+		ws[ch] = file
+	}
+	stems, err := sn76489.NewWAVStems(ws, sampleRate, format)
+	if err != nil {
+		closeAll()
+		return nil, nil, err
+	}
+	return stems, closeAll, nil
+}
+
+// rawWriter writes headerless little-endian PCM.
+type rawWriter struct {
+	w      io.Writer
+	format sn76489.WAVFormat
+	buf    []byte
+}
+
+func (r *rawWriter) WriteSamples(samples []float32) error {
+	r.buf = r.buf[:0]
+	for _, v := range samples {
+		if r.format == sn76489.WAVInt16 {
+			v = max(-1, min(1, v))
+			r.buf = binary.LittleEndian.AppendUint16(r.buf, uint16(int16(math.Round(float64(v)*math.MaxInt16))))
+		} else {
+			r.buf = binary.LittleEndian.AppendUint32(r.buf, math.Float32bits(v))
+		}
+	}
+	_, err := r.w.Write(r.buf)
+	return err
+}
+
+// memWriteSeeker is an in-memory io.WriteSeeker used to build a WAV file
+// for a non-seekable destination.
+type memWriteSeeker struct {
+	buf []byte
+	pos int
+}
+
+func (m *memWriteSeeker) Write(p []byte) (int, error) {
+	if end := m.pos + len(p); end > len(m.buf) {
+		m.buf = append(m.buf, make([]byte, end-len(m.buf))...)
+	}
+	n := copy(m.buf[m.pos:], p)
+	m.pos += n
+	return n, nil
+}
+
+func (m *memWriteSeeker) Seek(offset int64, whence int) (int64, error) {
+	pos := int64(m.pos)
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos += offset
+	case io.SeekEnd:
+		pos = int64(len(m.buf)) + offset
+	}
+	if pos < 0 {
+		return 0, errors.New("negative seek position")
+	}
+	m.pos = int(pos)
+	return pos, nil
+}
+
+// filter processes output samples one at a time.
+type filter interface {
+	process(x float32) float32
+}
+
+// newFilter builds the filter selected by the -filter flag.
+func newFilter(name string, cutoff float64, sampleRate int) (filter, error) {
+	switch strings.ToLower(name) {
+	case "none":
+		return noFilter{}, nil
+	case "dc":
+		return &dcBlocker{r: 0.995}, nil
+	case "lowpass":
+		if cutoff <= 0 {
+			return nil, errors.New("lowpass cutoff must be positive")
+		}
+		a := 1 - math.Exp(-2*math.Pi*cutoff/float64(sampleRate))
+		return &lowpass{a: float32(a)}, nil
+	}
+	return nil, fmt.Errorf("unknown -filter %q", name)
+}
+
+// noFilter passes samples through unchanged.
+type noFilter struct{}
+
+func (noFilter) process(x float32) float32 { return x }
+
+// dcBlocker removes the DC offset of the chip's unipolar output.
+type dcBlocker struct {
+	r     float32
+	prevX float32
+	prevY float32
+}
+
+func (d *dcBlocker) process(x float32) float32 {
+	y := x - d.prevX + d.r*d.prevY
+	d.prevX = x
+	d.prevY = y
+	return y
+}
+
+// lowpass is a one-pole low-pass filter.
+type lowpass struct {
+	a float32
+	y float32
+}
+
+func (l *lowpass) process(x float32) float32 {
+	l.y += l.a * (x - l.y)
+	return l.y
+}
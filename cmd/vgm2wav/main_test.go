@@ -0,0 +1,296 @@
+package main
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/user-none/go-chip-sn76489"
+)
+
+// testClock gives one internal chip tick (16 input clocks) per 44.1 kHz
+// sample.
+const testClock = 16 * 44100
+
+// captureWriter collects rendered samples.
+type captureWriter struct {
+	samples []float32
+}
+
+func (c *captureWriter) WriteSamples(samples []float32) error {
+	c.samples = append(c.samples, samples...)
+	return nil
+}
+
+// testOptions returns the flag defaults used by the tests.
+func testOptions() options {
+	return options{format: "wav", bits: 16, sampleRate: 44100, variant: "auto", loops: 2, fade: 0, gain: 1, filter: "none"}
+}
+
+// toneFile returns a file playing tone 0 (register 10, volume 0) for 4410
+// samples (0.1 s), optionally looping the whole tone.
+func toneFile(loop bool) *sn76489.VGMFile {
+	f := &sn76489.VGMFile{
+		ClockFreq:    testClock,
+		TotalSamples: 4410,
+		LoopOffset:   -1,
+		Data: []byte{
+			0x50, 0x8A, // Tone 0 = 10
+			0x50, 0x00,
+			0x50, 0x90, // Volume 0
+			0x61, 0x3A, 0x11, // Wait 4410 samples
+			0x66,
+		},
+	}
+	if loop {
+		f.LoopOffset = 0
+		f.LoopSamples = 4410
+	}
+	return f
+}
+
+// peak returns the largest absolute sample value.
+func peak(samples []float32) float32 {
+	var p float32
+	for _, v := range samples {
+		p = max(p, float32(math.Abs(float64(v))))
+	}
+	return p
+}
+
+// TestNewFilter verifies the filter selection and the response of each
+// filter.
+func TestNewFilter(t *testing.T) {
+	none, err := newFilter("none", 0, 44100)
+	if err != nil || none.process(0.7) != 0.7 {
+		t.Errorf("none: expected passthrough, err=%v", err)
+	}
+
+	// The DC blocker passes a step and then decays it toward zero.
+	dc, err := newFilter("DC", 0, 44100)
+	if err != nil {
+		t.Fatalf("dc: %v", err)
+	}
+	if y := dc.process(1); y != 1 {
+		t.Errorf("dc: expected the step to pass, got %f", y)
+	}
+	var y float32
+	for i := 0; i < 5000; i++ {
+		y = dc.process(1)
+	}
+	if math.Abs(float64(y)) > 1e-3 {
+		t.Errorf("dc: expected constant input to decay to 0, got %f", y)
+	}
+
+	// The one-pole low-pass moves a fraction of the way per sample and
+	// settles on the input.
+	lp, err := newFilter("lowpass", 1000, 44100)
+	if err != nil {
+		t.Fatalf("lowpass: %v", err)
+	}
+	a := 1 - math.Exp(-2*math.Pi*1000/44100)
+	if y := lp.process(1); math.Abs(float64(y)-a) > 1e-6 {
+		t.Errorf("lowpass: expected first output %f, got %f", a, y)
+	}
+	for i := 0; i < 1000; i++ {
+		y = lp.process(1)
+	}
+	if math.Abs(float64(y)-1) > 1e-3 {
+		t.Errorf("lowpass: expected to settle at 1, got %f", y)
+	}
+
+	if _, err := newFilter("lowpass", 0, 44100); err == nil {
+		t.Error("lowpass: expected an error for a zero cutoff")
+	}
+	if _, err := newFilter("reverb", 0, 44100); err == nil {
+		t.Error("Expected an error for an unknown filter")
+	}
+}
+
+// TestChipConfig verifies -variant resolution, including the fallback to
+// Sega for a header without noise fields.
+func TestChipConfig(t *testing.T) {
+	ti := &sn76489.VGMFile{ShiftWidth: 15, Feedback: 0x0003, Flags: 0x01}
+	tests := []struct {
+		file    *sn76489.VGMFile
+		variant string
+		want    sn76489.Config
+	}{
+		{ti, "auto", sn76489.TI},
+		{&sn76489.VGMFile{}, "auto", sn76489.Sega},
+		{ti, "Sega", sn76489.Sega},
+		{&sn76489.VGMFile{}, "ti", sn76489.TI},
+	}
+	for _, tt := range tests {
+		got, err := chipConfig(tt.file, tt.variant)
+		if err != nil || got != tt.want {
+			t.Errorf("chipConfig(%q): expected %+v, got %+v (err=%v)", tt.variant, tt.want, got, err)
+		}
+	}
+	if _, err := chipConfig(ti, "ym2612"); err == nil {
+		t.Error("Expected an error for an unknown variant")
+	}
+}
+
+// TestRender_MuteMask verifies muted channels are left out of the mix.
+func TestRender_MuteMask(t *testing.T) {
+	opts := testOptions()
+	var out captureWriter
+	if err := render(toneFile(false), sn76489.Sega, opts, noFilter{}, &out, nil); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if len(out.samples) != 4410 || peak(out.samples) == 0 {
+		t.Fatalf("Unmuted: expected 4410 samples with signal, got %d (peak %f)", len(out.samples), peak(out.samples))
+	}
+
+	opts.mute = 0x1
+	out = captureWriter{}
+	if err := render(toneFile(false), sn76489.Sega, opts, noFilter{}, &out, nil); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if p := peak(out.samples); p != 0 {
+		t.Errorf("Tone 0 muted: expected silence, got peak %f", p)
+	}
+
+	opts.mute = 0xE
+	out = captureWriter{}
+	if err := render(toneFile(false), sn76489.Sega, opts, noFilter{}, &out, nil); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if p := peak(out.samples); p == 0 {
+		t.Error("Other channels muted: expected tone 0 to remain")
+	}
+}
+
+// TestRender_FadeAndGain verifies the gain, the loop count and the fade
+// length for a looping file.
+func TestRender_FadeAndGain(t *testing.T) {
+	opts := testOptions()
+	opts.gain = 0.5
+	opts.loops = 2
+	opts.fade = 0.1
+
+	var out captureWriter
+	if err := render(toneFile(true), sn76489.Sega, opts, noFilter{}, &out, nil); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	// Two passes of 4410 samples, then 4410 samples of fade.
+	mainLen, fadeLen := 2*4410, 4410
+	if len(out.samples) != mainLen+fadeLen {
+		t.Fatalf("Expected %d samples, got %d", mainLen+fadeLen, len(out.samples))
+	}
+	if p := peak(out.samples[:mainLen]); p != 0.5 {
+		t.Errorf("Gain 0.5: expected peak 0.5 before the fade, got %f", p)
+	}
+	if p := peak(out.samples[mainLen+fadeLen/2:]); p > 0.25 {
+		t.Errorf("Second half of the fade: expected peak <= 0.25, got %f", p)
+	}
+	if p := peak(out.samples[mainLen+fadeLen-100:]); p > 0.5*100/float32(fadeLen)+1e-6 {
+		t.Errorf("End of the fade: expected near silence, got peak %f", p)
+	}
+}
+
+// TestRender_Stems verifies stems carry the gain and fade, so they sum to
+// the main output.
+func TestRender_Stems(t *testing.T) {
+	var mems [4]*memWriteSeeker
+	var ws [4]io.WriteSeeker
+	for ch := range mems {
+		mems[ch] = &memWriteSeeker{}
+		ws[ch] = mems[ch]
+	}
+	stems, err := sn76489.NewWAVStems(ws, 44100, sn76489.WAVFloat32)
+	if err != nil {
+		t.Fatalf("NewWAVStems: %v", err)
+	}
+	opts := testOptions()
+	opts.gain = 0.5
+	opts.fade = 0.1
+
+	var out captureWriter
+	if err := render(toneFile(true), sn76489.Sega, opts, noFilter{}, &out, stems); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if err := stems.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// Float WAVs have a 58-byte header (fmt extension and fact chunk).
+	const header = 58
+	for i, want := range out.samples {
+		var sum float32
+		for ch := range mems {
+			sum += math.Float32frombits(binary.LittleEndian.Uint32(mems[ch].buf[header+4*i:]))
+		}
+		if math.Abs(float64(sum-want)) > 1e-6 {
+			t.Fatalf("Sample %d: stems sum to %f, main output %f", i, sum, want)
+		}
+	}
+}
+
+// TestRender_WAV renders into a WAV writer end to end and checks the
+// header sizes match the rendered length.
+func TestRender_WAV(t *testing.T) {
+	mem := &memWriteSeeker{}
+	w, err := sn76489.NewWAVWriter(mem, 44100, 1, sn76489.WAVInt16)
+	if err != nil {
+		t.Fatalf("NewWAVWriter: %v", err)
+	}
+	opts := testOptions()
+	if err := render(toneFile(false), sn76489.Sega, opts, &dcBlocker{r: 0.995}, w, nil); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	buf := mem.buf
+	if len(buf) != 44+2*4410 {
+		t.Fatalf("Expected %d bytes, got %d", 44+2*4410, len(buf))
+	}
+	if string(buf[0:4]) != "RIFF" || string(buf[8:12]) != "WAVE" || string(buf[36:40]) != "data" {
+		t.Errorf("Bad WAV header: %q", buf[:44])
+	}
+	if n := binary.LittleEndian.Uint32(buf[40:]); n != 2*4410 {
+		t.Errorf("data size: expected %d, got %d", 2*4410, n)
+	}
+	// The DC-blocked square wave swings both ways.
+	var pos, neg bool
+	for i := 44; i < len(buf); i += 2 {
+		v := int16(binary.LittleEndian.Uint16(buf[i:]))
+		pos = pos || v > 0
+		neg = neg || v < 0
+	}
+	if !pos || !neg {
+		t.Error("Expected a DC-blocked signal with both polarities")
+	}
+}
+
+// TestRender_UnboundedLoop verifies a file without a length whose loop has
+// no LoopSamples still ends after the requested loops plus the fade.
+func TestRender_UnboundedLoop(t *testing.T) {
+	f := &sn76489.VGMFile{
+		ClockFreq:  testClock,
+		LoopOffset: 2,
+		Data: []byte{
+			0x50, 0x90, // Tone 0 volume 0
+			0x61, 0x10, 0x27, // Wait 10000 samples
+			0x66,
+		},
+	}
+	opts := testOptions()
+	opts.loops = 3
+	opts.fade = 0.5
+
+	var out captureWriter
+	if err := render(f, sn76489.Sega, opts, noFilter{}, &out, nil); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	// Three 10000-sample passes end within the 735-sample chunk after the
+	// last one, then the fade runs for 0.5 s.
+	want := 3*10000 + 44100/2
+	if n := len(out.samples); n < want || n > want+735 {
+		t.Errorf("Expected about %d samples, got %d", want, n)
+	}
+}
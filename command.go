@@ -0,0 +1,68 @@
+package sn76489
+
+// Command is a decoded byte written to the chip.
+//
+//	Latch/data byte: 1 CC T DDDD  (CC = channel, T = type, DDDD = data)
+//	Data byte:       0 X DDDDDD
+//
+// A data byte applies to the register selected by the most recent latch
+// byte, so Channel and Volume are only meaningful when Latch is true.
+type Command struct {
+	Latch   bool  // Latch/data byte (bit 7 set)
+	Channel int   // 0-2 = tone channels, 3 = noise (latch bytes only)
+	Volume  bool  // Volume register, false = tone/noise register (latch bytes only)
+	Data    uint8 // Latch: 4 data bits. Data byte: bits 0-6 (bit 6 is unused by the chip)
+}
+
+// DecodeCommand decodes a byte written to the chip. It does not depend on
+// any chip state.
+func DecodeCommand(b uint8) Command {
+	if b&0x80 == 0 {
+		return Command{Data: b & 0x7F}
+	}
+	return Command{
+		Latch:   true,
+		Channel: int(b>>5) & 0x03,
+		Volume:  b&0x10 != 0,
+		Data:    b & 0x0F,
+	}
+}
+
+// Encode returns the byte for c. DecodeCommand(b).Encode() == b for every
+// byte b.
+func (c Command) Encode() uint8 {
+	if !c.Latch {
+		return c.Data & 0x7F
+	}
+	b := 0x80 | uint8(c.Channel&0x03)<<5 | c.Data&0x0F
+	if c.Volume {
+		b |= 0x10
+	}
+	return b
+}
+
+// EncodeTone returns the latch and data bytes that set tone channel ch (0-2)
+// to the 10-bit period.
+func EncodeTone(ch int, period uint16) [2]uint8 {
+	return [2]uint8{
+		Command{Latch: true, Channel: ch, Data: uint8(period & 0x0F)}.Encode(),
+		uint8(period>>4) & 0x3F,
+	}
+}
+
+// EncodeVolume returns the byte that sets channel ch (0-3) to the 4-bit
+// volume (0 = max, 15 = off).
+func EncodeVolume(ch int, volume uint8) uint8 {
+	return Command{Latch: true, Channel: ch, Volume: true, Data: volume}.Encode()
+}
+
+// EncodeNoise returns the byte that sets the noise mode: white or periodic
+// feedback and shift rate 0-3 (3 = follow tone channel 2). Writing it
+// restarts the LFSR.
+func EncodeNoise(white bool, rate uint8) uint8 {
+	data := rate & 0x03
+	if white {
+		data |= 0x04
+	}
+	return Command{Latch: true, Channel: 3, Data: data}.Encode()
+}
@@ -0,0 +1,87 @@
+package sn76489
+
+import "testing"
+
+// TestDecodeCommand_RoundTrip verifies every byte decodes and re-encodes to
+// itself.
+func TestDecodeCommand_RoundTrip(t *testing.T) {
+	for v := 0; v < 256; v++ {
+		b := uint8(v)
+		c := DecodeCommand(b)
+		if got := c.Encode(); got != b {
+			t.Errorf("0x%02X: decoded %+v, re-encoded 0x%02X", b, c, got)
+		}
+		if c.Latch != (b&0x80 != 0) {
+			t.Errorf("0x%02X: Latch = %v", b, c.Latch)
+		}
+	}
+}
+
+// TestDecodeCommand_Fields verifies the decoded fields of each byte match
+// what the chip does with it.
+func TestDecodeCommand_Fields(t *testing.T) {
+	for v := 0x80; v < 0x100; v++ {
+		b := uint8(v)
+		c := DecodeCommand(b)
+		chip := New(3579545, 48000, 800, Sega)
+		chip.Write(b)
+		if int(chip.latchedChannel) != c.Channel || (chip.latchedType == 1) != c.Volume {
+			t.Errorf("0x%02X: decoded channel %d volume %v, chip latched %d/%d",
+				b, c.Channel, c.Volume, chip.latchedChannel, chip.latchedType)
+		}
+		if c.Data != b&0x0F {
+			t.Errorf("0x%02X: Data = 0x%X", b, c.Data)
+		}
+	}
+	for v := 0; v < 0x80; v++ {
+		if c := DecodeCommand(uint8(v)); c.Data != uint8(v) || c.Channel != 0 || c.Volume {
+			t.Errorf("0x%02X: data byte decoded as %+v", v, c)
+		}
+	}
+}
+
+// TestEncodeHelpers verifies the encoded sequences set the intended
+// registers on a chip.
+func TestEncodeHelpers(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	for ch := 0; ch < 3; ch++ {
+		for _, period := range []uint16{0, 1, 0x0F, 0x10, 0x155, 0x2AA, 0x3FF} {
+			b := EncodeTone(ch, period)
+			chip.Write(b[0])
+			chip.Write(b[1])
+			if got := chip.GetToneReg(ch); got != period {
+				t.Errorf("EncodeTone(%d, 0x%03X): register = 0x%03X", ch, period, got)
+			}
+		}
+	}
+	for ch := 0; ch < 4; ch++ {
+		for vol := uint8(0); vol < 16; vol++ {
+			chip.Write(EncodeVolume(ch, vol))
+			if got := chip.GetVolume(ch); got != vol {
+				t.Errorf("EncodeVolume(%d, %d): volume = %d", ch, vol, got)
+			}
+		}
+	}
+	for _, white := range []bool{false, true} {
+		for rate := uint8(0); rate < 4; rate++ {
+			chip.Write(EncodeNoise(white, rate))
+			want := rate
+			if white {
+				want |= 0x04
+			}
+			if got := chip.GetNoiseReg(); got != want {
+				t.Errorf("EncodeNoise(%v, %d): noise register = 0x%X", white, rate, got)
+			}
+		}
+	}
+
+	if got := EncodeTone(1, 0x3FE); got != [2]uint8{0xAE, 0x3F} {
+		t.Errorf("EncodeTone(1, 0x3FE): expected [AE 3F], got % X", got)
+	}
+	if got := EncodeVolume(3, 5); got != 0xF5 {
+		t.Errorf("EncodeVolume(3, 5): expected 0xF5, got 0x%02X", got)
+	}
+	if got := EncodeNoise(true, 3); got != 0xE7 {
+		t.Errorf("EncodeNoise(true, 3): expected 0xE7, got 0x%02X", got)
+	}
+}
@@ -0,0 +1,122 @@
+package sn76489
+
+import "math"
+
+// Debug access to internal state not covered by the register getters in
+// sn76489.go. Setters write fields directly, bypassing the latch protocol:
+// they do not move the latch, restart the LFSR or run the debug hooks.
+// Values are masked or clamped to the range the chip can produce (see
+// State.Validate), so a debugger cannot put the chip in an invalid state.
+
+// GetToneCounter returns the tone counter for the given channel (0-2).
+func (s *SN76489) GetToneCounter(ch int) uint16 {
+	return s.toneCounter[ch]
+}
+
+// GetNoiseCounter returns the noise counter.
+func (s *SN76489) GetNoiseCounter() uint16 {
+	return s.noiseCounter
+}
+
+// GetNoiseToggle returns the noise channel's internal toggle, which flips on
+// every noise counter reload; the LFSR shifts when it goes high.
+func (s *SN76489) GetNoiseToggle() bool {
+	return s.noiseToggle
+}
+
+// GetLatch returns the latched channel (0-3) and whether the volume register
+// (true) or tone/noise register (false) is latched.
+func (s *SN76489) GetLatch() (channel uint8, volume bool) {
+	return s.latchedChannel, s.latchedType == 1
+}
+
+// GetClockDivider returns the input clock divider phase (0-15).
+func (s *SN76489) GetClockDivider() int {
+	return s.clockDivider
+}
+
+// GetClockCounter returns the fractional input clocks accumulated toward the
+// next output sample.
+func (s *SN76489) GetClockCounter() float64 {
+	return s.clockCounter
+}
+
+// SetToneReg sets the tone register for the given channel (0-2), masked to
+// 10 bits.
+func (s *SN76489) SetToneReg(ch int, value uint16) {
+	s.toneReg[ch] = value & 0x3FF
+}
+
+// SetToneCounter sets the tone counter for the given channel (0-2), clamped
+// to 0x400.
+func (s *SN76489) SetToneCounter(ch int, value uint16) {
+	s.toneCounter[ch] = min(value, 0x400)
+}
+
+// SetToneOutput sets the output flip-flop for the given tone channel (0-2).
+func (s *SN76489) SetToneOutput(ch int, high bool) {
+	s.toneOutput[ch] = high
+}
+
+// SetVolume sets the volume for the given channel (0-3), masked to 4 bits.
+func (s *SN76489) SetVolume(ch int, value uint8) {
+	s.volume[ch] = value & 0x0F
+}
+
+// SetNoiseReg sets the noise control register, masked to 3 bits. Unlike a
+// write, it does not restart the LFSR.
+func (s *SN76489) SetNoiseReg(value uint8) {
+	s.noiseReg = value & 0x07
+}
+
+// SetNoiseCounter sets the noise counter, clamped to 0x400.
+func (s *SN76489) SetNoiseCounter(value uint16) {
+	s.noiseCounter = min(value, 0x400)
+}
+
+// SetNoiseShift sets the LFSR, masked to the variant's LFSR width (or to
+// the top bit of a wider custom LFSRInit).
+func (s *SN76489) SetNoiseShift(value uint16) {
+	limit := uint32(1) << (s.feedbackShift + 1)
+	for limit <= uint32(s.lfsrInitial) {
+		limit <<= 1
+	}
+	s.noiseShift = uint16(uint32(value) & (limit - 1))
+}
+
+// SetNoiseToggle sets the noise channel's internal toggle.
+func (s *SN76489) SetNoiseToggle(high bool) {
+	s.noiseToggle = high
+}
+
+// SetNoiseOutput sets the noise channel's audio output bit.
+func (s *SN76489) SetNoiseOutput(high bool) {
+	s.noiseOut = high
+}
+
+// SetLatch sets the latched channel (masked to 0-3) and register type, as a
+// latch byte would without changing any register.
+func (s *SN76489) SetLatch(channel uint8, volume bool) {
+	s.latchedChannel = channel & 0x03
+	s.latchedType = boolByte(volume)
+}
+
+// SetClockDivider sets the input clock divider phase, masked to 0-15.
+func (s *SN76489) SetClockDivider(value int) {
+	s.clockDivider = value & 0x0F
+}
+
+// SetClockCounter sets the fractional input clocks accumulated toward the
+// next output sample. Negative, NaN and infinite values are treated as 0.
+func (s *SN76489) SetClockCounter(value float64) {
+	if math.IsNaN(value) || math.IsInf(value, 0) || value < 0 {
+		value = 0
+	}
+	s.clockCounter = value
+}
+
+// SetClock sets the input clock position reported by GetClock and used to
+// timestamp events, traces and breakpoints.
+func (s *SN76489) SetClock(clock uint64) {
+	s.clocks = clock
+}
@@ -0,0 +1,133 @@
+package sn76489
+
+import (
+	"math"
+	"testing"
+)
+
+// TestSN76489_DebugGetters verifies the internal counters read back as the
+// chip advances.
+func TestSN76489_DebugGetters(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	writeTone(chip, 0, 10)
+	chip.Write(0xD2) // Latch channel 2 volume
+	chip.Run(16)     // One internal tick
+
+	if got := chip.GetToneCounter(0); got != 10 {
+		t.Errorf("GetToneCounter(0): expected 10, got %d", got)
+	}
+	if got := chip.GetNoiseCounter(); got != 0x10 {
+		t.Errorf("GetNoiseCounter: expected 0x10, got 0x%X", got)
+	}
+	if !chip.GetNoiseToggle() {
+		t.Error("GetNoiseToggle: expected high after the first reload")
+	}
+	if ch, vol := chip.GetLatch(); ch != 2 || !vol {
+		t.Errorf("GetLatch: expected (2, true), got (%d, %v)", ch, vol)
+	}
+	chip.Run(5)
+	if got := chip.GetClockDivider(); got != 5 {
+		t.Errorf("GetClockDivider: expected 5, got %d", got)
+	}
+	if got := chip.GetClockCounter(); got <= 0 || got >= chip.clocksPerSample {
+		t.Errorf("GetClockCounter: expected within (0, %f), got %f", chip.clocksPerSample, got)
+	}
+}
+
+// TestSN76489_DebugSetters verifies each setter round-trips through its
+// getter without touching the latch or LFSR.
+func TestSN76489_DebugSetters(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	chip.Write(0xE4) // White noise, latch channel 3 tone
+	chip.Run(1000)
+	shift := chip.GetNoiseShift()
+
+	chip.SetToneReg(1, 0x123)
+	chip.SetToneCounter(1, 0x45)
+	chip.SetToneOutput(1, true)
+	chip.SetVolume(3, 7)
+	chip.SetNoiseReg(0x01)
+	chip.SetNoiseCounter(0x20)
+	chip.SetNoiseToggle(true)
+	chip.SetNoiseOutput(true)
+	chip.SetClockDivider(9)
+	chip.SetClockCounter(12.5)
+	chip.SetClock(123456)
+
+	if chip.GetToneReg(1) != 0x123 || chip.GetToneCounter(1) != 0x45 || !chip.GetToneOutput(1) {
+		t.Error("Tone setters did not round-trip")
+	}
+	if chip.GetVolume(3) != 7 {
+		t.Errorf("SetVolume: expected 7, got %d", chip.GetVolume(3))
+	}
+	if chip.GetNoiseReg() != 0x01 || chip.GetNoiseShift() != shift {
+		t.Error("SetNoiseReg should not restart the LFSR")
+	}
+	if chip.GetNoiseCounter() != 0x20 || !chip.GetNoiseToggle() || !chip.GetNoiseOutput() {
+		t.Error("Noise setters did not round-trip")
+	}
+	if ch, vol := chip.GetLatch(); ch != 3 || vol {
+		t.Errorf("Setters moved the latch to (%d, %v)", ch, vol)
+	}
+	if chip.GetClockDivider() != 9 || chip.GetClockCounter() != 12.5 || chip.GetClock() != 123456 {
+		t.Error("Clock setters did not round-trip")
+	}
+
+	chip.SetNoiseShift(0x1234)
+	chip.SetLatch(1, true)
+	if chip.GetNoiseShift() != 0x1234 {
+		t.Errorf("SetNoiseShift: expected 0x1234, got 0x%X", chip.GetNoiseShift())
+	}
+	chip.Write(0x05) // Data byte goes to the new latch
+	if chip.GetVolume(1) != 5 {
+		t.Errorf("Data after SetLatch: expected volume 5, got %d", chip.GetVolume(1))
+	}
+}
+
+// TestSN76489_DebugSettersMask verifies out-of-range values are masked or
+// clamped so the state always validates.
+func TestSN76489_DebugSettersMask(t *testing.T) {
+	chip := New(3579545, 48000, 800, TI)
+	chip.SetToneReg(0, 0xFFFF)
+	chip.SetToneCounter(0, 0xFFFF)
+	chip.SetVolume(0, 0xFF)
+	chip.SetNoiseReg(0xFF)
+	chip.SetNoiseCounter(0xFFFF)
+	chip.SetNoiseShift(0xFFFF)
+	chip.SetLatch(0xFF, false)
+	chip.SetClockDivider(-1)
+	chip.SetClockCounter(math.NaN())
+
+	if got := chip.GetToneReg(0); got != 0x3FF {
+		t.Errorf("SetToneReg: expected 0x3FF, got 0x%X", got)
+	}
+	if got := chip.GetToneCounter(0); got != 0x400 {
+		t.Errorf("SetToneCounter: expected 0x400, got 0x%X", got)
+	}
+	if got := chip.GetVolume(0); got != 0x0F {
+		t.Errorf("SetVolume: expected 0x0F, got 0x%X", got)
+	}
+	if got := chip.GetNoiseReg(); got != 0x07 {
+		t.Errorf("SetNoiseReg: expected 0x07, got 0x%X", got)
+	}
+	if got := chip.GetNoiseCounter(); got != 0x400 {
+		t.Errorf("SetNoiseCounter: expected 0x400, got 0x%X", got)
+	}
+	if got := chip.GetNoiseShift(); got != 0x7FFF {
+		t.Errorf("SetNoiseShift: expected 15-bit 0x7FFF, got 0x%X", got)
+	}
+	if ch, _ := chip.GetLatch(); ch != 3 {
+		t.Errorf("SetLatch: expected channel 3, got %d", ch)
+	}
+	if got := chip.GetClockDivider(); got != 15 {
+		t.Errorf("SetClockDivider: expected 15, got %d", got)
+	}
+	if got := chip.GetClockCounter(); got != 0 {
+		t.Errorf("SetClockCounter(NaN): expected 0, got %f", got)
+	}
+
+	st := chip.SaveState()
+	if err := chip.validateState(&st); err != nil {
+		t.Errorf("State after masked setters is invalid: %v", err)
+	}
+}
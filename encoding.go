@@ -0,0 +1,75 @@
+package sn76489
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler using the Serialize
+// format.
+func (s *SN76489) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, SerializeSize)
+	if err := s.Serialize(buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. The chip must have
+// been created with New; see Deserialize.
+func (s *SN76489) UnmarshalBinary(data []byte) error {
+	return s.Deserialize(data)
+}
+
+// WriteTo implements io.WriterTo, writing the Serialize format to w.
+func (s *SN76489) WriteTo(w io.Writer) (int64, error) {
+	buf, err := s.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(buf)
+	return int64(n), err
+}
+
+// ReadFrom implements io.ReaderFrom. It reads exactly one serialized state
+// from r, sized by its version byte, so several states can be read from one
+// stream. The chip is left unchanged on error.
+func (s *SN76489) ReadFrom(r io.Reader) (int64, error) {
+	var version [1]byte
+	if _, err := io.ReadFull(r, version[:]); err != nil {
+		return 0, err
+	}
+	size, ok := serializeSizes[version[0]]
+	if !ok {
+		return 1, ErrUnsupportedVersion
+	}
+
+	buf := make([]byte, size)
+	buf[0] = version[0]
+	n, err := io.ReadFull(r, buf[1:])
+	if err != nil {
+		return int64(n) + 1, err
+	}
+	return int64(size), s.Deserialize(buf)
+}
+
+// MarshalJSON encodes the chip state (see State) as JSON, for inspecting
+// save files.
+func (s *SN76489) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.SaveState())
+}
+
+// UnmarshalJSON restores chip state from JSON produced by MarshalJSON.
+// Fields missing from the JSON keep their current values. The state is
+// validated like Deserialize and the chip is left unchanged on error.
+func (s *SN76489) UnmarshalJSON(data []byte) error {
+	st := s.SaveState()
+	if err := json.Unmarshal(data, &st); err != nil {
+		return err
+	}
+	if err := s.validateState(&st); err != nil {
+		return err
+	}
+	s.LoadState(st)
+	return nil
+}
@@ -0,0 +1,143 @@
+package sn76489
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*SN76489)(nil)
+	_ encoding.BinaryUnmarshaler = (*SN76489)(nil)
+	_ io.WriterTo                = (*SN76489)(nil)
+	_ io.ReaderFrom              = (*SN76489)(nil)
+	_ json.Marshaler             = (*SN76489)(nil)
+	_ json.Unmarshaler           = (*SN76489)(nil)
+)
+
+// TestSN76489_BinaryMarshal verifies MarshalBinary matches Serialize and
+// UnmarshalBinary restores the state.
+func TestSN76489_BinaryMarshal(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	setupSerializeState(chip)
+
+	data, err := chip.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, SerializeSize)
+	if err := chip.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, buf) {
+		t.Error("MarshalBinary differs from Serialize")
+	}
+
+	chip2 := New(3579545, 48000, 800, Sega)
+	if err := chip2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if chip2.SaveState() != chip.SaveState() {
+		t.Error("UnmarshalBinary did not restore the state")
+	}
+}
+
+// TestSN76489_WriteToReadFrom verifies several states can be streamed
+// through one writer and read back in order, including an older version.
+func TestSN76489_WriteToReadFrom(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	var stream bytes.Buffer
+
+	first := chip.SaveState()
+	if n, err := chip.WriteTo(&stream); err != nil || n != SerializeSize {
+		t.Fatalf("WriteTo: n=%d err=%v", n, err)
+	}
+	setupSerializeState(chip)
+	second := chip.SaveState()
+	if _, err := chip.WriteTo(&stream); err != nil {
+		t.Fatal(err)
+	}
+	v1, err := os.ReadFile("testdata/serialize/v1_active_sega.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Write(v1)
+
+	reader := New(3579545, 48000, 800, Sega)
+	if n, err := reader.ReadFrom(&stream); err != nil || n != SerializeSize {
+		t.Fatalf("ReadFrom first: n=%d err=%v", n, err)
+	}
+	if reader.SaveState() != first {
+		t.Error("First state mismatch")
+	}
+	if _, err := reader.ReadFrom(&stream); err != nil {
+		t.Fatal(err)
+	}
+	if reader.SaveState() != second {
+		t.Error("Second state mismatch")
+	}
+	if n, err := reader.ReadFrom(&stream); err != nil || n != int64(len(v1)) {
+		t.Fatalf("ReadFrom v1: n=%d err=%v", n, err)
+	}
+	if _, err := reader.ReadFrom(&stream); err != io.EOF {
+		t.Errorf("ReadFrom at end: expected io.EOF, got %v", err)
+	}
+}
+
+// TestSN76489_ReadFromErrors verifies unknown versions and truncated
+// streams are reported.
+func TestSN76489_ReadFromErrors(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	if _, err := chip.ReadFrom(bytes.NewReader([]byte{99, 0, 0})); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Bad version: expected ErrUnsupportedVersion, got %v", err)
+	}
+
+	data, _ := chip.MarshalBinary()
+	if _, err := chip.ReadFrom(bytes.NewReader(data[:20])); err != io.ErrUnexpectedEOF {
+		t.Errorf("Truncated: expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+// TestSN76489_JSON verifies the JSON form is readable and round-trips.
+func TestSN76489_JSON(t *testing.T) {
+	chip := New(3579545, 48000, 800, TI)
+	setupSerializeState(chip)
+
+	data, err := json.Marshal(chip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{`"toneReg":[427,1013,162]`, `"noiseReg":5`, `"volume":[0,3,7,11]`, `"latchedChannel"`} {
+		if !strings.Contains(string(data), key) {
+			t.Errorf("JSON missing %s: %s", key, data)
+		}
+	}
+
+	chip2 := New(3579545, 48000, 800, TI)
+	if err := json.Unmarshal(data, chip2); err != nil {
+		t.Fatal(err)
+	}
+	if chip2.SaveState() != chip.SaveState() {
+		t.Error("JSON round trip did not restore the state")
+	}
+
+	// Partial JSON edits one field; out-of-range values are rejected.
+	if err := json.Unmarshal([]byte(`{"volume":[15,15,15,1]}`), chip2); err != nil {
+		t.Fatal(err)
+	}
+	if chip2.GetVolume(3) != 1 || chip2.GetToneReg(0) != 0x1AB {
+		t.Errorf("Partial JSON: volume3=%d toneReg0=0x%X", chip2.GetVolume(3), chip2.GetToneReg(0))
+	}
+	before := chip2.SaveState()
+	if err := json.Unmarshal([]byte(`{"latchedChannel":7}`), chip2); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Bad latch: expected ErrInvalidState, got %v", err)
+	}
+	if chip2.SaveState() != before {
+		t.Error("Chip changed on rejected JSON")
+	}
+}
@@ -0,0 +1,94 @@
+package sn76489
+
+// EventKind identifies what changed in an Event.
+type EventKind int
+
+const (
+	EventTonePeriod EventKind = iota // Tone register of channel 0-2 changed
+	EventVolume                      // Volume of channel 0-3 changed
+	EventNoiseMode                   // Noise register (feedback and shift rate) changed
+	EventNoiseReset                  // Noise register written, restarting the LFSR
+)
+
+// String returns the event kind's name.
+func (k EventKind) String() string {
+	switch k {
+	case EventTonePeriod:
+		return "tone"
+	case EventVolume:
+		return "volume"
+	case EventNoiseMode:
+		return "noise mode"
+	case EventNoiseReset:
+		return "noise reset"
+	}
+	return "unknown"
+}
+
+// Event is a decoded change to the chip's musical state, delivered to
+// subscribers as Write processes it.
+type Event struct {
+	Kind    EventKind
+	Clock   uint64 // Input clock position (see GetClock)
+	Channel int    // 0-2 = tone channels, 3 = noise
+	Value   uint16 // New tone register, volume or noise register
+	Old     uint16 // Previous value (equal to Value for EventNoiseReset)
+}
+
+// eventSubscriber is a registered event callback.
+type eventSubscriber struct {
+	id int
+	fn func(Event)
+}
+
+// Subscribe registers fn to receive an Event for every change a Write makes:
+// a new tone period or volume, a new noise mode, and every noise register
+// write (which restarts the LFSR, even if the mode is unchanged). Writes
+// that leave a register unchanged produce no event. Events carry the clock
+// position, so writes between Run calls are timestamped exactly.
+//
+// Callbacks run synchronously inside Write and must not write to the chip.
+// Returns a function that removes the subscription. Subscriptions are not
+// copied by Clone.
+func (s *SN76489) Subscribe(fn func(Event)) (unsubscribe func()) {
+	s.nextSubID++
+	id := s.nextSubID
+	s.subscribers = append(s.subscribers, eventSubscriber{id: id, fn: fn})
+	return func() {
+		for i, sub := range s.subscribers {
+			if sub.id == id {
+				s.subscribers = append(s.subscribers[:i:i], s.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// writeEvents delivers the events for a write, given the register values
+// before it.
+func (s *SN76489) writeEvents(before *Registers) {
+	ch := int(s.latchedChannel)
+	switch {
+	case s.latchedType == 1:
+		if s.volume[ch] != before.Volume[ch] {
+			s.emit(Event{Kind: EventVolume, Channel: ch, Value: uint16(s.volume[ch]), Old: uint16(before.Volume[ch])})
+		}
+	case ch < 3:
+		if s.toneReg[ch] != before.ToneReg[ch] {
+			s.emit(Event{Kind: EventTonePeriod, Channel: ch, Value: s.toneReg[ch], Old: before.ToneReg[ch]})
+		}
+	default:
+		if s.noiseReg != before.NoiseReg {
+			s.emit(Event{Kind: EventNoiseMode, Channel: 3, Value: uint16(s.noiseReg), Old: uint16(before.NoiseReg)})
+		}
+		s.emit(Event{Kind: EventNoiseReset, Channel: 3, Value: uint16(s.noiseReg), Old: uint16(s.noiseReg)})
+	}
+}
+
+// emit timestamps e and delivers it to all subscribers.
+func (s *SN76489) emit(e Event) {
+	e.Clock = s.clocks
+	for _, sub := range s.subscribers {
+		sub.fn(e)
+	}
+}
@@ -0,0 +1,175 @@
+package sn76489
+
+import "testing"
+
+// TestSN76489_GetClock verifies the clock counter follows Clock, Run and
+// GenerateSamples and is cleared by a power-cycle reset only.
+func TestSN76489_GetClock(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	chip.Clock()
+	chip.Run(100)
+	chip.GenerateSamples(1000)
+	if got := chip.GetClock(); got != 1101 {
+		t.Errorf("GetClock: expected 1101, got %d", got)
+	}
+	if got := chip.Clone().GetClock(); got != 1101 {
+		t.Errorf("Clone: expected clock 1101, got %d", got)
+	}
+	chip.ResetWith(ResetChipLine)
+	if got := chip.GetClock(); got != 1101 {
+		t.Errorf("ResetChipLine: expected clock 1101, got %d", got)
+	}
+	chip.Reset()
+	if got := chip.GetClock(); got != 0 {
+		t.Errorf("Reset: expected clock 0, got %d", got)
+	}
+}
+
+// TestSN76489_GetClockAcrossRestore verifies CopyStateFrom and Rewind roll
+// the clock timestamp back with the state, while LoadState and Deserialize,
+// whose State format has no timestamp, leave it alone.
+func TestSN76489_GetClockAcrossRestore(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	chip.Run(1000)
+	saved := chip.Clone()
+	st := chip.SaveState()
+	buf := make([]byte, SerializeSize)
+	if err := chip.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	rw := NewRewind(4, 2)
+	rw.Push(0, chip)
+
+	restores := []struct {
+		name    string
+		restore func()
+		want    uint64
+	}{
+		{"LoadState", func() { chip.LoadState(st) }, 6000},
+		{"Deserialize", func() {
+			if err := chip.Deserialize(buf); err != nil {
+				t.Fatal(err)
+			}
+		}, 6000},
+		{"CopyStateFrom", func() { chip.CopyStateFrom(saved) }, 1000},
+		{"Rewind.Seek", func() { rw.Seek(0, chip) }, 1000},
+		{"Rewind.Pop", func() { rw.Pop(chip) }, 1000},
+	}
+	for _, r := range restores {
+		chip.SetClock(1000)
+		chip.Run(5000)
+		r.restore()
+		if got := chip.GetClock(); got != r.want {
+			t.Errorf("%s: expected clock %d, got %d", r.name, r.want, got)
+		}
+	}
+}
+
+// TestSN76489_TraceAfterRollback verifies a trace after a rollback and
+// re-run lines up with a run that had no rollback.
+func TestSN76489_TraceAfterRollback(t *testing.T) {
+	var plain, rolled []WriteTrace
+	a := New(3579545, 48000, 800, Sega)
+	b := New(3579545, 48000, 800, Sega)
+	a.SetWriteTrace(func(w WriteTrace) { plain = append(plain, w) })
+	b.SetWriteTrace(func(w WriteTrace) { rolled = append(rolled, w) })
+
+	a.Run(1000)
+	a.Write(0x90)
+	a.Run(1000)
+	a.Write(0x91)
+
+	b.Run(1000)
+	snapshot := b.Clone()
+	b.Write(0x9F) // Mispredicted frame, rolled back
+	b.Run(3000)
+	b.CopyStateFrom(snapshot)
+	rolled = rolled[:0]
+	b.Write(0x90)
+	b.Run(1000)
+	b.Write(0x91)
+
+	if len(plain) != len(rolled) {
+		t.Fatalf("Expected %d writes, got %d", len(plain), len(rolled))
+	}
+	for i := range plain {
+		if plain[i] != rolled[i] {
+			t.Errorf("Write %d: expected %+v, got %+v", i, plain[i], rolled[i])
+		}
+	}
+}
+
+// TestSN76489_SubscribeEvents verifies decoded events, their timestamps,
+// and that unchanged writes produce none.
+func TestSN76489_SubscribeEvents(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	var events []Event
+	chip.Subscribe(func(e Event) { events = append(events, e) })
+
+	chip.Write(0x8E) // Tone 0 low nibble: 0x000 -> 0x00E
+	chip.Run(100)
+	chip.Write(0x0F) // Tone 0 high bits: 0x00E -> 0x0FE
+	chip.Write(0x0F) // Unchanged: no event
+	chip.Write(0xB3) // Volume 1: 15 -> 3
+	chip.Run(50)
+	chip.Write(0xE4) // Noise mode 0 -> 4, LFSR reset
+	chip.Write(0xE4) // Mode unchanged, LFSR reset only
+	chip.Write(0x9F) // Volume 0 unchanged at 15: no event
+
+	want := []Event{
+		{Kind: EventTonePeriod, Clock: 0, Channel: 0, Value: 0x00E, Old: 0x000},
+		{Kind: EventTonePeriod, Clock: 100, Channel: 0, Value: 0x0FE, Old: 0x00E},
+		{Kind: EventVolume, Clock: 100, Channel: 1, Value: 3, Old: 15},
+		{Kind: EventNoiseMode, Clock: 150, Channel: 3, Value: 4, Old: 0},
+		{Kind: EventNoiseReset, Clock: 150, Channel: 3, Value: 4, Old: 4},
+		{Kind: EventNoiseReset, Clock: 150, Channel: 3, Value: 4, Old: 4},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("Expected %d events, got %d: %+v", len(want), len(events), events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("Event %d: expected %+v, got %+v", i, want[i], events[i])
+		}
+	}
+}
+
+// TestSN76489_Unsubscribe verifies multiple subscribers and removal, and
+// that subscriptions are not carried over by Clone.
+func TestSN76489_Unsubscribe(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	var a, b int
+	unsubA := chip.Subscribe(func(Event) { a++ })
+	chip.Subscribe(func(Event) { b++ })
+
+	chip.Write(0x90)
+	unsubA()
+	unsubA() // Second call is a no-op
+	chip.Write(0x91)
+	if a != 1 || b != 2 {
+		t.Errorf("Expected a=1 b=2, got a=%d b=%d", a, b)
+	}
+
+	clone := chip.Clone()
+	clone.Write(0x92)
+	if b != 2 {
+		t.Error("Clone should not deliver events to the original's subscribers")
+	}
+}
+
+// TestSN76489_EventsMatchPlainWrite verifies subscribing does not change
+// chip behavior.
+func TestSN76489_EventsMatchPlainWrite(t *testing.T) {
+	plain := New(3579545, 48000, 800, TI)
+	observed := New(3579545, 48000, 800, TI)
+	observed.Subscribe(func(Event) {})
+	for v := 0; v < 256; v++ {
+		plain.Write(uint8(v))
+		observed.Write(uint8(v))
+		plain.Run(17)
+		observed.Run(17)
+		if plain.SaveState() != observed.SaveState() {
+			t.Fatalf("State differs after writing 0x%02X", v)
+		}
+	}
+}
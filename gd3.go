@@ -0,0 +1,95 @@
+package sn76489
+
+import (
+	"encoding/binary"
+	"errors"
+	"unicode/utf16"
+)
+
+const gd3Version = 0x00000100
+
+// GD3 holds the metadata tag stored at the end of a VGM file. Each name has
+// an English and a Japanese form; either may be empty.
+type GD3 struct {
+	TrackName    string
+	TrackNameJP  string
+	GameName     string
+	GameNameJP   string
+	SystemName   string
+	SystemNameJP string
+	Author       string
+	AuthorJP     string
+	ReleaseDate  string // Free-form, conventionally yyyy/mm/dd, yyyy/mm or yyyy
+	Ripper       string // Name of the person who created the VGM
+	Notes        string
+}
+
+// GD3Field is one named tag field, used by Fields for listing and editing.
+type GD3Field struct {
+	Name  string
+	Value *string
+}
+
+// Fields returns the tag fields in file order with pointers into g, so a
+// caller can print or edit them generically.
+func (g *GD3) Fields() []GD3Field {
+	return []GD3Field{
+		{"Track", &g.TrackName},
+		{"Track (JP)", &g.TrackNameJP},
+		{"Game", &g.GameName},
+		{"Game (JP)", &g.GameNameJP},
+		{"System", &g.SystemName},
+		{"System (JP)", &g.SystemNameJP},
+		{"Author", &g.Author},
+		{"Author (JP)", &g.AuthorJP},
+		{"Release date", &g.ReleaseDate},
+		{"Ripper", &g.Ripper},
+		{"Notes", &g.Notes},
+	}
+}
+
+// MarshalBinary encodes the tag as a GD3 block ("Gd3 ", version, length,
+// then eleven null-terminated UTF-16LE strings).
+func (g *GD3) MarshalBinary() ([]byte, error) {
+	var body []byte
+	for _, f := range g.Fields() {
+		for _, u := range utf16.Encode([]rune(*f.Value)) {
+			body = binary.LittleEndian.AppendUint16(body, u)
+		}
+		body = append(body, 0, 0)
+	}
+
+	buf := make([]byte, 12, 12+len(body))
+	copy(buf, "Gd3 ")
+	binary.LittleEndian.PutUint32(buf[4:], gd3Version)
+	binary.LittleEndian.PutUint32(buf[8:], uint32(len(body)))
+	return append(buf, body...), nil
+}
+
+// UnmarshalBinary decodes a GD3 block produced by MarshalBinary or found in
+// a VGM file. Trailing strings missing from a truncated tag are left empty.
+func (g *GD3) UnmarshalBinary(data []byte) error {
+	if len(data) < 12 || string(data[0:4]) != "Gd3 " {
+		return errors.New("sn76489: invalid GD3 tag")
+	}
+	length := binary.LittleEndian.Uint32(data[8:])
+	if uint64(length) > uint64(len(data)-12) {
+		return errors.New("sn76489: GD3 tag truncated")
+	}
+	body := data[12 : 12+length]
+
+	*g = GD3{}
+	for _, f := range g.Fields() {
+		var units []uint16
+		for len(body) >= 2 {
+			u := binary.LittleEndian.Uint16(body)
+			body = body[2:]
+			if u == 0 {
+				break
+			}
+			units = append(units, u)
+		}
+		*f.Value = string(utf16.Decode(units))
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package sn76489
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// testGD3 returns a tag with every field set, including non-ASCII text.
+func testGD3() *GD3 {
+	return &GD3{
+		TrackName:    "Green Hill Zone",
+		TrackNameJP:  "グリーンヒルゾーン",
+		GameName:     "Sonic the Hedgehog",
+		GameNameJP:   "ソニック・ザ・ヘッジホッグ",
+		SystemName:   "Sega Master System",
+		SystemNameJP: "セガマスターシステム",
+		Author:       "Yuzo Koshiro",
+		AuthorJP:     "古代祐三",
+		ReleaseDate:  "1991/10/25",
+		Ripper:       "tester",
+		Notes:        "Line one\nLine two 🎵",
+	}
+}
+
+// TestGD3_RoundTrip verifies MarshalBinary/UnmarshalBinary preserve every field.
+func TestGD3_RoundTrip(t *testing.T) {
+	tag := testGD3()
+	buf, err := tag.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf[0:4]) != "Gd3 " {
+		t.Errorf("Bad magic %q", buf[0:4])
+	}
+	if got := binary.LittleEndian.Uint32(buf[4:]); got != gd3Version {
+		t.Errorf("Version: expected 0x%X, got 0x%X", gd3Version, got)
+	}
+	if got := binary.LittleEndian.Uint32(buf[8:]); int(got) != len(buf)-12 {
+		t.Errorf("Length: expected %d, got %d", len(buf)-12, got)
+	}
+
+	var loaded GD3
+	if err := loaded.UnmarshalBinary(buf); err != nil {
+		t.Fatal(err)
+	}
+	if loaded != *tag {
+		t.Errorf("Round trip mismatch:\noriginal=%+v\nloaded=%+v", *tag, loaded)
+	}
+}
+
+// TestGD3_EmptyTag verifies an empty tag encodes as eleven empty strings.
+func TestGD3_EmptyTag(t *testing.T) {
+	buf, err := (&GD3{}).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buf) != 12+11*2 {
+		t.Errorf("Empty tag size: expected %d, got %d", 12+11*2, len(buf))
+	}
+}
+
+// TestGD3_Invalid verifies bad magic and truncated tags are rejected.
+func TestGD3_Invalid(t *testing.T) {
+	buf, _ := testGD3().MarshalBinary()
+
+	var tag GD3
+	if err := tag.UnmarshalBinary(buf[:8]); err == nil {
+		t.Error("UnmarshalBinary should reject a short header")
+	}
+	if err := tag.UnmarshalBinary(buf[:len(buf)-2]); err == nil {
+		t.Error("UnmarshalBinary should reject a truncated body")
+	}
+	bad := append([]byte{}, buf...)
+	copy(bad, "Gd4 ")
+	if err := tag.UnmarshalBinary(bad); err == nil {
+		t.Error("UnmarshalBinary should reject bad magic")
+	}
+}
+
+// TestGD3_Fields verifies Fields exposes every field by pointer for editing.
+func TestGD3_Fields(t *testing.T) {
+	tag := &GD3{}
+	fields := tag.Fields()
+	if len(fields) != 11 {
+		t.Fatalf("Expected 11 fields, got %d", len(fields))
+	}
+	*fields[0].Value = "edited"
+	*fields[10].Value = "notes"
+	if tag.TrackName != "edited" || tag.Notes != "notes" {
+		t.Errorf("Edits through Fields not applied: %+v", *tag)
+	}
+}
+
+// TestGD3_VGMRoundTrip verifies a tag written by the logger is read back by
+// ParseVGM and can be edited and rewritten.
+func TestGD3_VGMRoundTrip(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	logger := NewVGMLogger(chip)
+	logger.SetGD3(testGD3())
+	logger.Write(0x90)
+	logger.Run(100000)
+
+	var out bytes.Buffer
+	if _, err := logger.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := ParseVGM(out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.GD3 == nil || *f.GD3 != *testGD3() {
+		t.Fatalf("Parsed tag mismatch: %+v", f.GD3)
+	}
+	if f.Data[len(f.Data)-1] != vgmCmdEnd {
+		t.Error("Command stream should stop before the GD3 tag")
+	}
+
+	f.GD3.Ripper = "someone else"
+	edited, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f2, err := ParseVGM(edited)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f2.GD3 == nil || f2.GD3.Ripper != "someone else" || f2.GD3.GameName != "Sonic the Hedgehog" {
+		t.Errorf("Edited tag not preserved: %+v", f2.GD3)
+	}
+	if !bytes.Equal(f2.Data, f.Data) {
+		t.Error("Command stream changed when editing the tag")
+	}
+}
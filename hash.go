@@ -0,0 +1,73 @@
+package sn76489
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// FNV-1a 64-bit parameters.
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+// stateHashSize is the size of the chip state encoding hashed by
+// StateHash.
+const stateHashSize = 29
+
+// StateHash returns a 64-bit FNV-1a hash of the chip state, for netplay
+// desync detection. It covers the registers, counters, outputs, LFSR, latch
+// and clock divider. Host configuration is excluded: gain, clock, sample
+// rate, output buffers and the sample-rate accumulator (State.ClockCounter),
+// so peers with different audio devices agree. The hash is independent of
+// host byte order and does not allocate.
+func (s *SN76489) StateHash() uint64 {
+	var buf [stateHashSize]byte
+	b := buf[:0]
+	for ch := 0; ch < 3; ch++ {
+		b = binary.LittleEndian.AppendUint16(b, s.toneReg[ch])
+		b = binary.LittleEndian.AppendUint16(b, s.toneCounter[ch])
+		b = append(b, boolByte(s.toneOutput[ch]))
+	}
+	b = append(b, s.noiseReg)
+	b = binary.LittleEndian.AppendUint16(b, s.noiseCounter)
+	b = binary.LittleEndian.AppendUint16(b, s.noiseShift)
+	b = append(b, boolByte(s.noiseToggle), boolByte(s.noiseOut))
+	b = append(b, s.volume[:]...)
+	b = append(b, s.latchedChannel, s.latchedType, uint8(s.clockDivider))
+	return fnvBytes(fnvOffset64, b)
+}
+
+// StateHashWithOutput returns StateHash extended over the samples generated
+// since the last buffer reset: the buffer position and each channel's
+// pre-gain output. Comparing it between peers after each frame catches
+// desyncs that only show up in the audio.
+func (s *SN76489) StateHashWithOutput() uint64 {
+	h := s.StateHash()
+	h = fnvUint32(h, uint32(s.bufferPos))
+	for ch := range s.channelBuffers {
+		for _, v := range s.channelBuffers[ch][:s.bufferPos] {
+			h = fnvUint32(h, math.Float32bits(v))
+		}
+	}
+	return h
+}
+
+// fnvBytes continues FNV-1a hash h over b.
+func fnvBytes(h uint64, b []byte) uint64 {
+	for _, c := range b {
+		h ^= uint64(c)
+		h *= fnvPrime64
+	}
+	return h
+}
+
+// fnvUint32 continues FNV-1a hash h over v in little-endian byte order.
+func fnvUint32(h uint64, v uint32) uint64 {
+	for i := 0; i < 4; i++ {
+		h ^= uint64(v & 0xFF)
+		h *= fnvPrime64
+		v >>= 8
+	}
+	return h
+}
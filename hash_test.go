@@ -0,0 +1,141 @@
+package sn76489
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"testing"
+)
+
+// TestSN76489_StateHashMatchesFNV verifies StateHash is FNV-1a over a fixed
+// little-endian layout, so peers on any platform agree.
+func TestSN76489_StateHashMatchesFNV(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	setupSerializeState(chip)
+
+	var b []byte
+	for ch := 0; ch < 3; ch++ {
+		b = binary.LittleEndian.AppendUint16(b, chip.GetToneReg(ch))
+		b = binary.LittleEndian.AppendUint16(b, chip.GetToneCounter(ch))
+		b = append(b, boolByte(chip.GetToneOutput(ch)))
+	}
+	b = append(b, chip.GetNoiseReg())
+	b = binary.LittleEndian.AppendUint16(b, chip.GetNoiseCounter())
+	b = binary.LittleEndian.AppendUint16(b, chip.GetNoiseShift())
+	b = append(b, boolByte(chip.GetNoiseToggle()), boolByte(chip.GetNoiseOutput()))
+	for ch := 0; ch < 4; ch++ {
+		b = append(b, chip.GetVolume(ch))
+	}
+	latch, volume := chip.GetLatch()
+	b = append(b, latch, boolByte(volume), uint8(chip.GetClockDivider()))
+	if len(b) != stateHashSize {
+		t.Fatalf("Layout: expected %d bytes, got %d", stateHashSize, len(b))
+	}
+
+	h := fnv.New64a()
+	h.Write(b)
+	if got, want := chip.StateHash(), h.Sum64(); got != want {
+		t.Errorf("StateHash: expected %#x, got %#x", want, got)
+	}
+}
+
+// TestSN76489_StateHashSampleRate verifies chips at different output sample
+// rates hash the same after the same writes and clocks.
+func TestSN76489_StateHashSampleRate(t *testing.T) {
+	a := New(3579545, 44100, 800, Sega)
+	b := New(3579545, 48000, 800, Sega)
+	for _, c := range []*SN76489{a, b} {
+		writeTone(c, 0, 0x0FE)
+		c.Write(0x90)
+		c.Write(0xE4)
+		c.GenerateSamples(59659)
+	}
+	if a.GetClockCounter() == b.GetClockCounter() {
+		t.Fatal("Test needs differing sample accumulators")
+	}
+	if a.StateHash() != b.StateHash() {
+		t.Error("StateHash should not depend on the output sample rate")
+	}
+}
+
+// TestSN76489_StateHashDetectsChanges verifies every kind of state change
+// alters the hash and that host audio config does not.
+func TestSN76489_StateHashDetectsChanges(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	setupSerializeState(chip)
+	base := chip.StateHash()
+
+	other := chip.Clone()
+	if other.StateHash() != base {
+		t.Fatal("Clone should have an identical hash")
+	}
+
+	other.SetGain(0.9)
+	other.ResetBuffer()
+	other.clockCounter += 0.5
+	if other.StateHash() != base {
+		t.Error("Gain, buffer position and sample accumulator should not affect StateHash")
+	}
+
+	mutations := map[string]func(c *SN76489){
+		"tone reg":      func(c *SN76489) { c.toneReg[1]++ },
+		"tone counter":  func(c *SN76489) { c.toneCounter[2]++ },
+		"tone output":   func(c *SN76489) { c.toneOutput[0] = !c.toneOutput[0] },
+		"noise reg":     func(c *SN76489) { c.noiseReg ^= 1 },
+		"noise counter": func(c *SN76489) { c.noiseCounter++ },
+		"noise shift":   func(c *SN76489) { c.noiseShift ^= 1 },
+		"noise toggle":  func(c *SN76489) { c.noiseToggle = !c.noiseToggle },
+		"noise out":     func(c *SN76489) { c.noiseOut = !c.noiseOut },
+		"volume":        func(c *SN76489) { c.volume[3] ^= 1 },
+		"latch":         func(c *SN76489) { c.latchedChannel ^= 1 },
+		"clock divider": func(c *SN76489) { c.clockDivider ^= 1 },
+	}
+	for name, mutate := range mutations {
+		c := chip.Clone()
+		mutate(c)
+		if c.StateHash() == base {
+			t.Errorf("%s: change not reflected in StateHash", name)
+		}
+	}
+}
+
+// TestSN76489_StateHashWithOutput verifies the output-covering hash tracks
+// generated samples and ignores gain.
+func TestSN76489_StateHashWithOutput(t *testing.T) {
+	a := New(3579545, 48000, 800, Sega)
+	a.Write(0x8F)
+	a.Write(0x0F)
+	a.Write(0x90)
+	b := a.Clone()
+	b.SetGain(0.8)
+
+	a.GenerateSamples(59659)
+	b.GenerateSamples(59659)
+	if a.StateHashWithOutput() != b.StateHashWithOutput() {
+		t.Fatal("Identical chips should have identical output hashes")
+	}
+	if a.StateHashWithOutput() == a.StateHash() {
+		t.Error("Output hash should cover the generated samples")
+	}
+
+	// Same final state, different output during the frame.
+	b.channelBuffers[0][10] = 0
+	if a.StateHash() != b.StateHash() {
+		t.Fatal("StateHash should not cover output")
+	}
+	if a.StateHashWithOutput() == b.StateHashWithOutput() {
+		t.Error("Output difference not reflected in StateHashWithOutput")
+	}
+}
+
+// TestSN76489_StateHashNoAlloc verifies hashing does not allocate.
+func TestSN76489_StateHashNoAlloc(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	chip.GenerateSamples(59659)
+	allocs := testing.AllocsPerRun(100, func() {
+		chip.StateHash()
+		chip.StateHashWithOutput()
+	})
+	if allocs != 0 {
+		t.Errorf("Expected 0 allocations, got %v", allocs)
+	}
+}
@@ -0,0 +1,25 @@
+package sn76489
+
+// writeHooked applies a write and runs the debug hooks: event subscribers,
+// the register timeline, the write trace, then breakpoints.
+func (s *SN76489) writeHooked(value uint8) {
+	before := s.GetRegisters()
+	s.write(value)
+
+	if len(s.subscribers) > 0 {
+		s.writeEvents(&before)
+	}
+	if s.timelineOn && s.GetRegisters() != before {
+		s.recordTimeline()
+	}
+	if s.writeTrace == nil && len(s.breakpoints) == 0 {
+		return
+	}
+	w := s.decodeWrite(value)
+	if s.writeTrace != nil {
+		s.writeTrace(w)
+	}
+	if len(s.breakpoints) > 0 {
+		s.checkWriteBreakpoints(w)
+	}
+}
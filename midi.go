@@ -0,0 +1,279 @@
+package sn76489
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// MIDI status bytes and meta event types used by the exporter and player.
+const (
+	midiNoteOff       = 0x80
+	midiNoteOn        = 0x90
+	midiPolyPressure  = 0xA0
+	midiControlChange = 0xB0
+	midiProgramChange = 0xC0
+	midiChanPressure  = 0xD0
+	midiPitchBend     = 0xE0
+	midiSysEx         = 0xF0
+	midiSysExEscape   = 0xF7
+	midiMeta          = 0xFF
+
+	midiMetaTrackName = 0x03
+	midiMetaEndTrack  = 0x2F
+	midiMetaTempo     = 0x51
+	midiMetaTimeSig   = 0x58
+
+	midiPercussionChannel = 9
+)
+
+// MIDIFile is a Standard MIDI File.
+type MIDIFile struct {
+	Format   int         // 0 = single track, 1 = simultaneous tracks
+	Division uint16      // Ticks per quarter note (SMPTE timing is not supported)
+	Tracks   []MIDITrack // Track 0 holds tempo events in format 1
+}
+
+// MIDITrack is a track's events in tick order.
+type MIDITrack []MIDIEvent
+
+// MIDIEvent is a single MIDI, meta or SysEx event.
+type MIDIEvent struct {
+	Tick   uint64 // Absolute time in ticks from the start of the track
+	Status uint8  // Status byte: 0x80-0xEF channel message, 0xF0/0xF7 SysEx, 0xFF meta
+	Meta   uint8  // Meta event type when Status is 0xFF
+	Data   []byte // Channel message data bytes, or the meta/SysEx payload
+}
+
+// Channel returns the MIDI channel (0-15) of a channel message.
+func (e MIDIEvent) Channel() int {
+	return int(e.Status & 0x0F)
+}
+
+// Kind returns the channel message type (0x80-0xE0), or the status byte for
+// SysEx and meta events.
+func (e MIDIEvent) Kind() uint8 {
+	if e.Status >= 0xF0 {
+		return e.Status
+	}
+	return e.Status & 0xF0
+}
+
+// ReadMIDI reads and parses a Standard MIDI File from r.
+func ReadMIDI(r io.Reader) (*MIDIFile, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMIDI(data)
+}
+
+// ParseMIDI parses a Standard MIDI File. Running status is expanded, so
+// every returned event has its status byte. Unknown chunks are skipped.
+func ParseMIDI(data []byte) (*MIDIFile, error) {
+	if len(data) < 14 || string(data[0:4]) != "MThd" {
+		return nil, errors.New("sn76489: not a MIDI file")
+	}
+	hdrLen := int(binary.BigEndian.Uint32(data[4:]))
+	if hdrLen < 6 || 8+hdrLen > len(data) {
+		return nil, errors.New("sn76489: MIDI header truncated")
+	}
+	f := &MIDIFile{
+		Format:   int(binary.BigEndian.Uint16(data[8:])),
+		Division: binary.BigEndian.Uint16(data[12:]),
+	}
+	ntrks := int(binary.BigEndian.Uint16(data[10:]))
+	if f.Format > 2 {
+		return nil, errors.New("sn76489: unsupported MIDI format")
+	}
+	if f.Division&0x8000 != 0 || f.Division == 0 {
+		return nil, errors.New("sn76489: SMPTE MIDI timing not supported")
+	}
+
+	pos := 8 + hdrLen
+	for len(f.Tracks) < ntrks {
+		if pos+8 > len(data) {
+			return nil, errors.New("sn76489: MIDI file truncated")
+		}
+		size := int(binary.BigEndian.Uint32(data[pos+4:]))
+		body := pos + 8
+		if size < 0 || body+size > len(data) {
+			return nil, errors.New("sn76489: MIDI chunk truncated")
+		}
+		if string(data[pos:pos+4]) == "MTrk" {
+			track, err := parseMIDITrack(data[body : body+size])
+			if err != nil {
+				return nil, err
+			}
+			f.Tracks = append(f.Tracks, track)
+		}
+		pos = body + size
+	}
+	return f, nil
+}
+
+var errMIDITruncated = errors.New("sn76489: MIDI track truncated")
+
+// parseMIDITrack decodes the events of an MTrk chunk body.
+func parseMIDITrack(data []byte) (MIDITrack, error) {
+	var track MIDITrack
+	var tick uint64
+	var running uint8
+	pos := 0
+	for pos < len(data) {
+		delta, n := readVLQ(data[pos:])
+		if n == 0 {
+			return nil, errMIDITruncated
+		}
+		pos += n
+		tick += delta
+		if pos >= len(data) {
+			return nil, errMIDITruncated
+		}
+
+		e := MIDIEvent{Tick: tick, Status: data[pos]}
+		switch {
+		case e.Status == midiMeta:
+			if pos+2 > len(data) {
+				return nil, errMIDITruncated
+			}
+			e.Meta = data[pos+1]
+			length, n := readVLQ(data[pos+2:])
+			start := pos + 2 + n
+			if n == 0 || length > uint64(len(data)-start) {
+				return nil, errMIDITruncated
+			}
+			e.Data = data[start : start+int(length)]
+			pos = start + int(length)
+		case e.Status == midiSysEx || e.Status == midiSysExEscape:
+			length, n := readVLQ(data[pos+1:])
+			start := pos + 1 + n
+			if n == 0 || length > uint64(len(data)-start) {
+				return nil, errMIDITruncated
+			}
+			e.Data = data[start : start+int(length)]
+			pos = start + int(length)
+			running = 0
+		case e.Status >= 0xF0:
+			return nil, errors.New("sn76489: invalid MIDI status in file")
+		default:
+			if e.Status&0x80 != 0 {
+				running = e.Status
+				pos++
+			} else if running == 0 {
+				return nil, errors.New("sn76489: MIDI data byte without status")
+			}
+			e.Status = running
+			size := 2
+			if kind := e.Kind(); kind == midiProgramChange || kind == midiChanPressure {
+				size = 1
+			}
+			if pos+size > len(data) {
+				return nil, errMIDITruncated
+			}
+			e.Data = data[pos : pos+size]
+			pos += size
+		}
+		track = append(track, e)
+		if e.Status == midiMeta && e.Meta == midiMetaEndTrack {
+			break
+		}
+	}
+	return track, nil
+}
+
+// readVLQ decodes a variable-length quantity, returning its value and
+// length, or length 0 if it is truncated or longer than 4 bytes.
+func readVLQ(data []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < len(data) && i < 4; i++ {
+		v = v<<7 | uint64(data[i]&0x7F)
+		if data[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
+
+// MarshalBinary encodes the file in Standard MIDI File format. An
+// end-of-track event is added to any track that does not end with one.
+func (f *MIDIFile) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("MThd")
+	var hdr [10]byte
+	binary.BigEndian.PutUint32(hdr[0:], 6)
+	binary.BigEndian.PutUint16(hdr[4:], uint16(f.Format))
+	binary.BigEndian.PutUint16(hdr[6:], uint16(len(f.Tracks)))
+	binary.BigEndian.PutUint16(hdr[8:], f.Division)
+	buf.Write(hdr[:])
+
+	for _, track := range f.Tracks {
+		var data []byte
+		var last uint64
+		ended := false
+		for _, e := range track {
+			data = appendVLQ(data, e.Tick-last)
+			last = e.Tick
+			data = e.append(data)
+			ended = e.Status == midiMeta && e.Meta == midiMetaEndTrack
+		}
+		if !ended {
+			data = append(data, 0x00, midiMeta, midiMetaEndTrack, 0x00)
+		}
+
+		buf.WriteString("MTrk")
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(len(data)))
+		buf.Write(size[:])
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteTo writes the encoded file to w.
+func (f *MIDIFile) WriteTo(w io.Writer) (int64, error) {
+	data, err := f.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// append appends the event, without its delta time, to data.
+func (e MIDIEvent) append(data []byte) []byte {
+	switch e.Status {
+	case midiMeta:
+		data = append(data, midiMeta, e.Meta)
+		data = appendVLQ(data, uint64(len(e.Data)))
+	case midiSysEx, midiSysExEscape:
+		data = append(data, e.Status)
+		data = appendVLQ(data, uint64(len(e.Data)))
+	default:
+		data = append(data, e.Status)
+	}
+	return append(data, e.Data...)
+}
+
+// appendVLQ appends v as a MIDI variable-length quantity.
+func appendVLQ(data []byte, v uint64) []byte {
+	var tmp [10]byte
+	i := len(tmp) - 1
+	tmp[i] = uint8(v & 0x7F)
+	for v >>= 7; v > 0; v >>= 7 {
+		i--
+		tmp[i] = uint8(v&0x7F) | 0x80
+	}
+	return append(data, tmp[i:]...)
+}
+
+// midiChannelEvent returns a channel message event.
+func midiChannelEvent(tick uint64, kind uint8, channel int, data ...byte) MIDIEvent {
+	return MIDIEvent{Tick: tick, Status: kind | uint8(channel), Data: data}
+}
+
+// midiMetaEvent returns a meta event.
+func midiMetaEvent(tick uint64, meta uint8, data []byte) MIDIEvent {
+	return MIDIEvent{Tick: tick, Status: midiMeta, Meta: meta, Data: data}
+}
@@ -0,0 +1,164 @@
+package sn76489
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestAppendVLQ verifies variable-length quantity encoding against the
+// examples in the SMF specification.
+func TestAppendVLQ(t *testing.T) {
+	tests := []struct {
+		v    uint64
+		want []byte
+	}{
+		{0x00, []byte{0x00}},
+		{0x40, []byte{0x40}},
+		{0x7F, []byte{0x7F}},
+		{0x80, []byte{0x81, 0x00}},
+		{0x2000, []byte{0xC0, 0x00}},
+		{0x3FFF, []byte{0xFF, 0x7F}},
+		{0x4000, []byte{0x81, 0x80, 0x00}},
+		{0x0FFFFFFF, []byte{0xFF, 0xFF, 0xFF, 0x7F}},
+	}
+	for _, tt := range tests {
+		if got := appendVLQ(nil, tt.v); !bytes.Equal(got, tt.want) {
+			t.Errorf("VLQ 0x%X: expected % X, got % X", tt.v, tt.want, got)
+		}
+	}
+}
+
+// TestMIDIFile_MarshalBinary verifies the header, delta times, meta and
+// channel event encoding, and the added end-of-track event.
+func TestMIDIFile_MarshalBinary(t *testing.T) {
+	f := &MIDIFile{
+		Format:   1,
+		Division: 480,
+		Tracks: []MIDITrack{
+			{
+				midiMetaEvent(0, midiMetaTrackName, []byte("A")),
+				midiChannelEvent(0, midiNoteOn, 2, 60, 100),
+				midiChannelEvent(200, midiNoteOff, 2, 60, 0),
+			},
+		},
+	}
+	got, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		'M', 'T', 'h', 'd', 0, 0, 0, 6, 0, 1, 0, 1, 0x01, 0xE0,
+		'M', 'T', 'r', 'k', 0, 0, 0, 18,
+		0x00, 0xFF, 0x03, 0x01, 'A',
+		0x00, 0x92, 60, 100,
+		0x81, 0x48, 0x82, 60, 0,
+		0x00, 0xFF, 0x2F, 0x00,
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Encoding mismatch:\nexpected % X\ngot      % X", want, got)
+	}
+
+	var buf bytes.Buffer
+	if n, err := f.WriteTo(&buf); err != nil || n != int64(len(want)) || !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("WriteTo: n=%d err=%v", n, err)
+	}
+}
+
+// TestParseMIDI_RoundTrip verifies a marshaled file parses back to the same
+// events, with the end-of-track event added.
+func TestParseMIDI_RoundTrip(t *testing.T) {
+	f := &MIDIFile{
+		Format:   1,
+		Division: 96,
+		Tracks: []MIDITrack{
+			{midiMetaEvent(0, midiMetaTempo, []byte{0x07, 0xA1, 0x20})},
+			{
+				midiChannelEvent(0, midiProgramChange, 3, 5),
+				midiChannelEvent(10, midiNoteOn, 3, 64, 90),
+				MIDIEvent{Tick: 20, Status: midiSysEx, Data: []byte{0x7E, 0x7F, 0xF7}},
+				midiChannelEvent(300, midiNoteOff, 3, 64, 0),
+			},
+		},
+	}
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseMIDI(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Format != 1 || got.Division != 96 || len(got.Tracks) != 2 {
+		t.Fatalf("Header: format=%d division=%d tracks=%d", got.Format, got.Division, len(got.Tracks))
+	}
+	for i, track := range f.Tracks {
+		parsed := got.Tracks[i]
+		if len(parsed) != len(track)+1 {
+			t.Fatalf("Track %d: expected %d events, got %d", i, len(track)+1, len(parsed))
+		}
+		for j, e := range track {
+			p := parsed[j]
+			if p.Tick != e.Tick || p.Status != e.Status || p.Meta != e.Meta || !bytes.Equal(p.Data, e.Data) {
+				t.Errorf("Track %d event %d: expected %+v, got %+v", i, j, e, p)
+			}
+		}
+		if end := parsed[len(parsed)-1]; end.Meta != midiMetaEndTrack {
+			t.Errorf("Track %d: missing end of track", i)
+		}
+	}
+
+	r, err := ReadMIDI(bytes.NewReader(data))
+	if err != nil || len(r.Tracks) != 2 {
+		t.Errorf("ReadMIDI: %v", err)
+	}
+}
+
+// TestParseMIDI_RunningStatus verifies data bytes without a status byte
+// reuse the previous status, and unknown chunks are skipped.
+func TestParseMIDI_RunningStatus(t *testing.T) {
+	data := []byte{
+		'M', 'T', 'h', 'd', 0, 0, 0, 6, 0, 0, 0, 1, 0, 96,
+		'X', 'Y', 'Z', 'W', 0, 0, 0, 2, 0xAA, 0xBB,
+		'M', 'T', 'r', 'k', 0, 0, 0, 14,
+		0x00, 0x91, 60, 100,
+		0x10, 64, 100, // Running status note on
+		0x10, 60, 0, // Running status note on, velocity 0
+		0x00, 0xFF, 0x2F, 0x00,
+	}
+	f, err := ParseMIDI(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	track := f.Tracks[0]
+	if len(track) != 4 {
+		t.Fatalf("Expected 4 events, got %+v", track)
+	}
+	for i, tick := range []uint64{0, 16, 32} {
+		if track[i].Status != 0x91 || track[i].Tick != tick {
+			t.Errorf("Event %d: expected status 0x91 at %d, got %+v", i, tick, track[i])
+		}
+	}
+	if track[1].Data[0] != 64 || track[2].Data[1] != 0 {
+		t.Errorf("Running status data: %+v", track)
+	}
+}
+
+// TestParseMIDI_Invalid verifies malformed files are rejected.
+func TestParseMIDI_Invalid(t *testing.T) {
+	header := []byte{'M', 'T', 'h', 'd', 0, 0, 0, 6, 0, 1, 0, 1, 0, 96}
+	tests := map[string][]byte{
+		"empty":         nil,
+		"not MIDI":      []byte("RIFF0000WAVEfmt "),
+		"SMPTE":         {'M', 'T', 'h', 'd', 0, 0, 0, 6, 0, 1, 0, 1, 0xE7, 0x28},
+		"missing track": header,
+		"short chunk":   append(append([]byte{}, header...), 'M', 'T', 'r', 'k', 0, 0, 0, 10, 0),
+		"no status":     append(append([]byte{}, header...), 'M', 'T', 'r', 'k', 0, 0, 0, 3, 0, 60, 100),
+		"truncated":     append(append([]byte{}, header...), 'M', 'T', 'r', 'k', 0, 0, 0, 3, 0, 0x90, 60),
+		"bad meta len":  append(append([]byte{}, header...), 'M', 'T', 'r', 'k', 0, 0, 0, 4, 0, 0xFF, 0x03, 0x05),
+	}
+	for name, data := range tests {
+		if _, err := ParseMIDI(data); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package sn76489
+
+import (
+	"io"
+	"math"
+)
+
+// MIDI export timing: 960 ticks per quarter note at 120 BPM, so one tick is
+// 1/1920 s.
+const (
+	midiExportDivision = 960
+	midiExportTempo    = 500000 // Microseconds per quarter note (120 BPM)
+	midiTicksPerSecond = midiExportDivision * 1000000 / midiExportTempo
+)
+
+// midiDrumNotes maps white noise shift rates 0-3 to General MIDI percussion
+// notes. Periodic noise, which sounds as a low buzz, plays a bass drum.
+var midiDrumNotes = [4]uint8{
+	42, // Rate 0 (fastest): closed hi-hat
+	38, // Rate 1: acoustic snare
+	45, // Rate 2: low tom
+	39, // Rate 3 (tone 2): hand clap
+}
+
+const midiPeriodicDrumNote = 36 // Bass drum 1
+
+// midiVoice is the MIDI note currently sounding for one PSG channel.
+type midiVoice struct {
+	on         bool
+	note       uint8
+	level      uint8  // Volume level at note-on, which set the velocity
+	expression uint8  // Last expression (CC 11) sent
+	bend       uint16 // Pitch bend value, 8192 = center; tone channels only
+}
+
+// MIDIExporter turns the register activity of an SN76489 into a format-1
+// Standard MIDI File. Tone channels 0-2 become tracks on MIDI channels 1-3;
+// the noise channel becomes a percussion track on MIDI channel 10.
+//
+// Like VGMLogger, it wraps a chip: route writes and clocking through the
+// exporter so it can timestamp each change. Writes at the same clock
+// position are coalesced, so the intermediate state between the latch and
+// data bytes of a tone write never produces a note.
+//
+// A tone channel sounds a note while its volume is not off and its period
+// gives a pitch. The note is the nearest MIDI note with a pitch bend for the
+// cent deviation (default ±2 semitone bend range). Velocity follows the
+// volume level at note-on. A held note that gets quieter is scaled with
+// expression (CC 11); one that gets louder than its velocity allows is
+// retriggered. Each track sets channel volume (CC 7) to 127 at the start, so
+// a General MIDI player reproduces the chip's levels exactly. The noise
+// channel triggers a drum on every noise register write (which restarts the
+// LFSR) or when unmuted.
+type MIDIExporter struct {
+	chip *SN76489
+
+	clocks     uint64 // Input clocks elapsed since export started
+	dirty      bool   // Writes at writeClock are not yet reflected in tracks
+	writeClock uint64
+	noiseHit   bool // Noise register written since the last update
+
+	voices [4]midiVoice
+	tracks [4]MIDITrack
+}
+
+// NewMIDIExporter creates an exporter that forwards to chip. Notes already
+// sounding on the chip start at time zero.
+func NewMIDIExporter(chip *SN76489) *MIDIExporter {
+	e := &MIDIExporter{chip: chip}
+	for ch := range e.voices {
+		e.voices[ch].bend = midiBendCenter
+		e.voices[ch].expression = 127 // General MIDI default
+	}
+	e.update(0)
+	return e
+}
+
+// VGMToMIDI converts the SN76489 writes of a VGM file to MIDI. The loop
+// section is played once. It returns an error if the file has no SN76489.
+func VGMToMIDI(f *VGMFile) (*MIDIFile, error) {
+	if f.ClockFreq == 0 {
+		return nil, errVGMNoPSG
+	}
+	e := NewMIDIExporter(New(f.ClockFreq, vgmSampleRate, 1, f.Config()))
+	var samples uint64
+	data := f.Data
+	for pos := 0; pos < len(data); {
+		op := data[pos]
+		size := vgmCommandSize(data[pos:])
+		if size == 0 || pos+size > len(data) || op == vgmCmdEnd {
+			break
+		}
+		arg := data[pos+1:]
+		switch {
+		case op == vgmCmdPSGWrite:
+			e.clocks = samples * uint64(f.ClockFreq) / vgmSampleRate
+			e.Write(arg[0])
+		case op == vgmCmdWait:
+			samples += uint64(arg[0]) | uint64(arg[1])<<8
+		case op == vgmCmdWait735:
+			samples += 735
+		case op == vgmCmdWait882:
+			samples += 882
+		case op&0xF0 == vgmCmdWaitN:
+			samples += uint64(op&0x0F) + 1
+		case op >= vgmCmdDACWaitMin && op <= vgmCmdDACWaitMax:
+			samples += uint64(op & 0x0F)
+		}
+		pos += size
+	}
+	e.clocks = samples * uint64(f.ClockFreq) / vgmSampleRate
+	return e.File(), nil
+}
+
+// Chip returns the wrapped chip.
+func (e *MIDIExporter) Chip() *SN76489 {
+	return e.chip
+}
+
+// Write writes value to the chip at the current clock position.
+func (e *MIDIExporter) Write(value uint8) {
+	if e.dirty && e.writeClock != e.clocks {
+		e.update(e.writeClock)
+	}
+	e.chip.Write(value)
+	if e.chip.latchedChannel == 3 && e.chip.latchedType == 0 {
+		e.noiseHit = true
+	}
+	e.dirty = true
+	e.writeClock = e.clocks
+}
+
+// Clock advances the chip by one input clock cycle.
+func (e *MIDIExporter) Clock() {
+	e.chip.Clock()
+	e.clocks++
+}
+
+// Run advances the chip by the given number of clocks. See SN76489.Run.
+func (e *MIDIExporter) Run(clocks int) int {
+	e.clocks += uint64(clocks)
+	return e.chip.Run(clocks)
+}
+
+// GenerateSamples resets the chip's buffer and runs the given number of
+// clocks. See SN76489.GenerateSamples.
+func (e *MIDIExporter) GenerateSamples(clocks int) int {
+	e.clocks += uint64(clocks)
+	return e.chip.GenerateSamples(clocks)
+}
+
+// ResetBuffer resets the chip's buffer position. See SN76489.ResetBuffer.
+func (e *MIDIExporter) ResetBuffer() {
+	e.chip.ResetBuffer()
+}
+
+// File returns the MIDI file for everything exported so far. Notes still
+// sounding end at the current clock position. Export can continue
+// afterwards.
+func (e *MIDIExporter) File() *MIDIFile {
+	if e.dirty {
+		e.update(e.writeClock)
+	}
+	end := e.tick(e.clocks)
+
+	conductor := MIDITrack{
+		midiMetaEvent(0, midiMetaTempo, []byte{midiExportTempo >> 16, midiExportTempo >> 8 & 0xFF, midiExportTempo & 0xFF}),
+		midiMetaEvent(0, midiMetaTimeSig, []byte{4, 2, 24, 8}),
+		midiMetaEvent(end, midiMetaEndTrack, nil),
+	}
+	f := &MIDIFile{Format: 1, Division: midiExportDivision, Tracks: []MIDITrack{conductor}}
+
+	names := [4]string{"Tone 1", "Tone 2", "Tone 3", "Noise"}
+	for ch, events := range e.tracks {
+		track := make(MIDITrack, 0, len(events)+4)
+		track = append(track, midiMetaEvent(0, midiMetaTrackName, []byte(names[ch])))
+		track = append(track, midiChannelEvent(0, midiControlChange, midiExportChannel(ch), midiCCVolume, 127))
+		track = append(track, events...)
+		if v := e.voices[ch]; v.on {
+			track = append(track, midiChannelEvent(end, midiNoteOff, midiExportChannel(ch), v.note, 0))
+		}
+		track = append(track, midiMetaEvent(end, midiMetaEndTrack, nil))
+		f.Tracks = append(f.Tracks, track)
+	}
+	return f
+}
+
+// WriteTo writes the MIDI file for everything exported so far to w.
+func (e *MIDIExporter) WriteTo(w io.Writer) (int64, error) {
+	return e.File().WriteTo(w)
+}
+
+// update emits the events that bring each channel's voice in line with the
+// chip state, timestamped at clock.
+func (e *MIDIExporter) update(clock uint64) {
+	tick := e.tick(clock)
+	for ch := 0; ch < 4; ch++ {
+		info := e.chip.AnalyzeChannel(ch)
+		v := &e.voices[ch]
+		midiCh := midiExportChannel(ch)
+
+		var note uint8
+		var bend uint16
+		audible := !info.Muted() && !info.Constant
+		if ch < 3 {
+			audible = audible && info.Note >= 0 && info.Note <= 127
+			if audible {
+				note = uint8(info.Note)
+				bend = midiBend(info.Cents)
+			}
+		} else if info.White {
+			note = midiDrumNotes[info.NoiseRate]
+		} else {
+			note = midiPeriodicDrumNote
+		}
+
+		retrigger := !v.on || note != v.note || (ch == 3 && e.noiseHit) || info.Volume < v.level
+		if v.on && (!audible || retrigger) {
+			e.tracks[ch] = append(e.tracks[ch], midiChannelEvent(tick, midiNoteOff, midiCh, v.note, 0))
+			v.on = false
+		}
+		if !audible {
+			continue
+		}
+		if ch < 3 && bend != v.bend {
+			e.tracks[ch] = append(e.tracks[ch], midiChannelEvent(tick, midiPitchBend, midiCh, uint8(bend&0x7F), uint8(bend>>7)))
+			v.bend = bend
+		}
+		expression := uint8(127)
+		if v.on {
+			expression = midiExpression(v.level, info.Volume)
+		}
+		if expression != v.expression {
+			e.tracks[ch] = append(e.tracks[ch], midiChannelEvent(tick, midiControlChange, midiCh, midiCCExpression, expression))
+			v.expression = expression
+		}
+		if !v.on {
+			e.tracks[ch] = append(e.tracks[ch], midiChannelEvent(tick, midiNoteOn, midiCh, note, midiAmplitude(info.Volume)))
+			v.on = true
+			v.note = note
+			v.level = info.Volume
+		}
+	}
+	e.noiseHit = false
+	e.dirty = false
+}
+
+// tick converts an input clock position to the nearest MIDI tick. A chip
+// with no clock frequency keeps everything at tick 0.
+func (e *MIDIExporter) tick(clock uint64) uint64 {
+	freq := uint64(e.chip.clockFreq)
+	if freq == 0 {
+		return 0
+	}
+	return (clock*midiTicksPerSecond + freq/2) / freq
+}
+
+// midiExportChannel returns the MIDI channel for PSG channel ch.
+func midiExportChannel(ch int) int {
+	if ch == 3 {
+		return midiPercussionChannel
+	}
+	return ch
+}
+
+// midiAmplitude maps a 4-bit volume level to a MIDI value (1-127), such as a
+// velocity, proportional to its amplitude.
+func midiAmplitude(level uint8) uint8 {
+	v := math.Round(127 * float64(volumeTable[level]))
+	return uint8(max(v, 1))
+}
+
+// midiExpression returns the expression (CC 11) value that scales a note
+// started at volume level from to the quieter level to.
+func midiExpression(from, to uint8) uint8 {
+	v := math.Round(127 * float64(volumeTable[to]) / float64(volumeTable[from]))
+	return uint8(min(max(v, 1), 127))
+}
+
+// midiBend returns the 14-bit pitch bend value for a deviation in cents,
+// assuming the default ±2 semitone bend range.
+func midiBend(cents float64) uint16 {
+	v := math.Round(8192 + cents/200*8192)
+	return uint16(min(max(v, 0), 16383))
+}
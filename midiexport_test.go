@@ -0,0 +1,218 @@
+package sn76489
+
+import (
+	"math"
+	"testing"
+)
+
+// channelEvents returns the channel messages of a track, skipping meta
+// events and the channel volume set at tick 0.
+func channelEvents(track MIDITrack) []MIDIEvent {
+	var events []MIDIEvent
+	for _, e := range track {
+		if e.Status >= midiSysEx || e.Tick == 0 && e.Kind() == midiControlChange && e.Data[0] == midiCCVolume {
+			continue
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+// TestMIDIExporter_ToneNotes verifies note-on/off and pitch bend for a tone
+// channel, with timing in ticks.
+func TestMIDIExporter_ToneNotes(t *testing.T) {
+	chip := New(3579545, 44100, 800, Sega)
+	e := NewMIDIExporter(chip)
+
+	// A4 (reg 254) at volume 0 for 0.5 s, then C5 (reg 214) for 0.5 s.
+	e.Write(0x8E) // Channel 0 tone low nibble = 0xE
+	e.Write(0x0F) // High bits: 0xFE = 254
+	e.Write(0x90)
+	e.GenerateSamples(3579545 / 2)
+	e.Write(0x86) // Low nibble = 6
+	e.Write(0x0D) // High bits: 0xD6 = 214
+	e.GenerateSamples(3579545 / 2)
+	e.Write(0x9F) // Mute
+	e.GenerateSamples(100)
+
+	f := e.File()
+	if f.Format != 1 || f.Division != 960 || len(f.Tracks) != 5 {
+		t.Fatalf("File: format=%d division=%d tracks=%d", f.Format, f.Division, len(f.Tracks))
+	}
+
+	for ch := 1; ch <= 4; ch++ {
+		if cc := f.Tracks[ch][1]; cc.Tick != 0 || cc.Kind() != midiControlChange || cc.Data[0] != midiCCVolume || cc.Data[1] != 127 {
+			t.Errorf("Track %d: expected channel volume 127 at tick 0, got %+v", ch, cc)
+		}
+	}
+
+	events := channelEvents(f.Tracks[1])
+	bendA4 := midiBend(1200 * math.Log2(3579545.0/(32*254)/440))
+	bendC5 := midiBend(1200*math.Log2(3579545.0/(32*214)/440) - 300)
+	want := []MIDIEvent{
+		midiChannelEvent(0, midiPitchBend, 0, uint8(bendA4&0x7F), uint8(bendA4>>7)),
+		midiChannelEvent(0, midiNoteOn, 0, 69, 127),
+		midiChannelEvent(960, midiNoteOff, 0, 69, 0),
+		midiChannelEvent(960, midiPitchBend, 0, uint8(bendC5&0x7F), uint8(bendC5>>7)),
+		midiChannelEvent(960, midiNoteOn, 0, 72, 127),
+		midiChannelEvent(1920, midiNoteOff, 0, 72, 0),
+	}
+	if len(events) != len(want) {
+		t.Fatalf("Expected %d events, got %d: %+v", len(want), len(events), events)
+	}
+	for i := range want {
+		if events[i].Tick != want[i].Tick || events[i].Status != want[i].Status || string(events[i].Data) != string(want[i].Data) {
+			t.Errorf("Event %d: expected %+v, got %+v", i, want[i], events[i])
+		}
+	}
+
+	for ch := 2; ch < 5; ch++ {
+		if n := len(channelEvents(f.Tracks[ch])); n != 0 {
+			t.Errorf("Track %d: expected no channel events, got %d", ch, n)
+		}
+	}
+}
+
+// TestMIDIExporter_VolumeChanges verifies volume changes on a held note
+// become expression changes, and a note still sounding is closed by File.
+func TestMIDIExporter_VolumeChanges(t *testing.T) {
+	e := NewMIDIExporter(New(3579545, 44100, 800, Sega))
+	e.Write(0xAE) // Channel 1 tone low nibble
+	e.Write(0x0F)
+	e.Write(0xB0)
+	e.Run(1000)
+	e.Write(0xB6) // Volume 6: -12 dB
+	e.Run(1000)
+	e.Write(0xB6) // Unchanged
+	e.Run(1000)
+
+	events := channelEvents(e.File().Tracks[2])
+	kinds := []uint8{midiPitchBend, midiNoteOn, midiControlChange, midiNoteOff}
+	if len(events) != len(kinds) {
+		t.Fatalf("Expected %d events, got %+v", len(kinds), events)
+	}
+	for i, k := range kinds {
+		if events[i].Kind() != k || events[i].Channel() != 1 {
+			t.Errorf("Event %d: expected kind 0x%X on channel 1, got %+v", i, k, events[i])
+		}
+	}
+	if cc, v := events[2].Data[0], events[2].Data[1]; cc != midiCCExpression || v != midiExpression(0, 6) || v != 32 {
+		t.Errorf("Expression: expected CC 11 = 32, got CC %d = %d", cc, v)
+	}
+}
+
+// TestMIDIExporter_Noise verifies noise writes trigger percussion notes on
+// MIDI channel 10, including a retrigger on an identical noise write.
+func TestMIDIExporter_Noise(t *testing.T) {
+	e := NewMIDIExporter(New(3579545, 44100, 800, Sega))
+	e.Write(0xE4) // White noise, rate 0
+	e.Write(0xF0)
+	e.Run(1000)
+	e.Write(0xE4) // Same setting: LFSR restart retriggers
+	e.Run(1000)
+	e.Write(0xE0) // Periodic noise
+	e.Run(1000)
+
+	events := channelEvents(e.File().Tracks[4])
+	want := []struct {
+		kind uint8
+		note uint8
+	}{
+		{midiNoteOn, 42}, {midiNoteOff, 42},
+		{midiNoteOn, 42}, {midiNoteOff, 42},
+		{midiNoteOn, 36}, {midiNoteOff, 36},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("Expected %d events, got %+v", len(want), events)
+	}
+	for i, w := range want {
+		if events[i].Kind() != w.kind || events[i].Data[0] != w.note || events[i].Channel() != midiPercussionChannel {
+			t.Errorf("Event %d: expected kind 0x%X note %d on channel 9, got %+v", i, w.kind, w.note, events[i])
+		}
+	}
+}
+
+// TestMIDIExporter_ConstantAndMutedSilent verifies constant-output and
+// muted channels produce no notes.
+func TestMIDIExporter_ConstantAndMutedSilent(t *testing.T) {
+	e := NewMIDIExporter(New(3579545, 44100, 800, Sega))
+	e.Write(0x90) // Channel 0 unmuted, tone reg 0 = constant on Sega
+	e.Write(0xA5) // Channel 1 tone set but muted
+	e.Write(0x10)
+	e.Run(1000)
+
+	f := e.File()
+	for ch := 1; ch <= 2; ch++ {
+		if n := len(channelEvents(f.Tracks[ch])); n != 0 {
+			t.Errorf("Track %d: expected no events, got %d", ch, n)
+		}
+	}
+}
+
+// TestVGMToMIDI verifies a logged VGM converts to notes at the logged
+// timing.
+func TestVGMToMIDI(t *testing.T) {
+	chip := New(3579545, 44100, 800, Sega)
+	logger := NewVGMLogger(chip)
+	logger.Write(0xC5) // Channel 2 tone
+	logger.Write(0x0A)
+	logger.Write(0xD2)
+	logger.GenerateSamples(3579545 / 4)
+	logger.Write(0xDF)
+	logger.GenerateSamples(3579545 / 4)
+
+	f, err := VGMToMIDI(logger.File())
+	if err != nil {
+		t.Fatalf("VGMToMIDI: %v", err)
+	}
+	events := channelEvents(f.Tracks[3])
+	kinds := []uint8{midiPitchBend, midiNoteOn, midiNoteOff}
+	if len(events) != len(kinds) {
+		t.Fatalf("Expected %d events, got %+v", len(kinds), events)
+	}
+	for i, k := range kinds {
+		if events[i].Kind() != k || events[i].Channel() != 2 {
+			t.Errorf("Event %d: expected kind 0x%X on channel 2, got %+v", i, k, events[i])
+		}
+	}
+	if events[1].Data[1] != midiAmplitude(2) {
+		t.Errorf("Velocity: expected %d for volume 2, got %d", midiAmplitude(2), events[1].Data[1])
+	}
+	if tick := events[2].Tick; tick < 479 || tick > 480 {
+		t.Errorf("Note off: expected tick ~480, got %d", tick)
+	}
+	if end := f.Tracks[3][len(f.Tracks[3])-1]; end.Meta != midiMetaEndTrack || end.Tick < 959 {
+		t.Errorf("End of track: expected tick ~960, got %+v", end)
+	}
+}
+
+// TestVGMToMIDI_NoPSG verifies a VGM file without an SN76489 is rejected
+// instead of dividing by its zero clock.
+func TestVGMToMIDI_NoPSG(t *testing.T) {
+	src := &VGMFile{
+		LoopOffset: -1,
+		Data:       []byte{0x52, 0x28, 0xF0, vgmCmdWait735, vgmCmdEnd}, // YM2612 only
+	}
+	data, err := src.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+	f, err := ParseVGM(data)
+	if err != nil {
+		t.Fatalf("ParseVGM: %v", err)
+	}
+	if f.ClockFreq != 0 {
+		t.Fatalf("ClockFreq: expected 0, got %d", f.ClockFreq)
+	}
+	if m, err := VGMToMIDI(f); err == nil || m != nil {
+		t.Errorf("Expected an error for a VGM with no SN76489, got %v", err)
+	}
+
+	// An exporter on a chip with no clock stays at tick 0.
+	e := NewMIDIExporter(New(0, 44100, 1, Sega))
+	e.Write(0x90)
+	e.Run(1000)
+	if end := e.File().Tracks[0]; end[len(end)-1].Tick != 0 {
+		t.Errorf("Zero-clock export: expected end at tick 0, got %d", end[len(end)-1].Tick)
+	}
+}
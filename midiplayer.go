@@ -0,0 +1,303 @@
+package sn76489
+
+import (
+	"math"
+	"sort"
+)
+
+// MIDI controllers and defaults used by the player.
+const (
+	midiCCVolume      = 7
+	midiCCExpression  = 11
+	midiCCAllSoundOff = 120
+	midiCCAllNotesOff = 123
+	midiDefaultVolume = 100
+	midiBendCenter    = 8192
+	midiBendSemitones = 2 // Default pitch bend range
+	midiMicrosPerSec  = 1000000
+)
+
+// midiTimedEvent is an event with its start time in chip clocks.
+type midiTimedEvent struct {
+	clock uint64
+	MIDIEvent
+}
+
+// midiPlayerVoice is a tone channel's allocation to a MIDI note.
+type midiPlayerVoice struct {
+	active  bool
+	channel int
+	note    uint8
+	vel     uint8
+	stamp   uint64 // Allocation order of the last note-on or note-off
+}
+
+// midiChannel is the controller state of a MIDI channel.
+type midiChannel struct {
+	bend       int // 14-bit pitch bend, 8192 = center
+	volume     uint8
+	expression uint8
+}
+
+// MIDIPlayer renders a Standard MIDI File through a chip. Notes on MIDI
+// channels other than 10 are allocated to the three tone channels; when all
+// are busy, the voice that started longest ago is stolen. Notes on channel
+// 10 (General MIDI percussion) play on the noise channel: bass drums as
+// periodic noise, other drums as white noise at a rate chosen by drum type.
+//
+// Pitch bend (±2 semitones), channel volume (CC 7), expression (CC 11) and
+// all-notes-off are applied to sounding notes. Velocity and the controllers
+// set the attenuation nearest to their combined amplitude. Program changes
+// are ignored: every instrument is a square wave.
+//
+// Like VGMPlayer, events are applied at their exact clock position within
+// each Run. Format 2 files are played as if all tracks were simultaneous.
+type MIDIPlayer struct {
+	chip   *SN76489
+	events []midiTimedEvent
+
+	pos    int    // Index of the next event
+	clocks uint64 // Chip clocks run since playback started
+	ended  bool
+
+	voices   [3]midiPlayerVoice
+	stamp    uint64
+	drumNote int // Sounding drum note, -1 for none
+	channels [16]midiChannel
+}
+
+// NewMIDIPlayer creates a player for f driving chip. Tone register values
+// are computed for the chip's clock frequency.
+func NewMIDIPlayer(f *MIDIFile, chip *SN76489) *MIDIPlayer {
+	p := &MIDIPlayer{chip: chip, drumNote: -1}
+	for ch := range p.channels {
+		p.channels[ch] = midiChannel{bend: midiBendCenter, volume: midiDefaultVolume, expression: 127}
+	}
+
+	var events []midiTimedEvent
+	for _, track := range f.Tracks {
+		for _, e := range track {
+			events = append(events, midiTimedEvent{MIDIEvent: e})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Tick < events[j].Tick
+	})
+
+	// Convert ticks to clocks through the tempo map.
+	var baseTick, baseMicros uint64
+	tempo := uint64(midiExportTempo)
+	division := uint64(max(f.Division, 1))
+	clockFreq := uint64(chip.clockFreq)
+	for i := range events {
+		e := &events[i]
+		micros := baseMicros + (e.Tick-baseTick)*tempo/division
+		e.clock = micros * clockFreq / midiMicrosPerSec
+		if e.Status == midiMeta && e.Meta == midiMetaTempo && len(e.Data) == 3 {
+			baseTick, baseMicros = e.Tick, micros
+			tempo = uint64(e.Data[0])<<16 | uint64(e.Data[1])<<8 | uint64(e.Data[2])
+		}
+	}
+	p.events = events
+	p.ended = len(events) == 0
+	return p
+}
+
+// ToneRegForFrequency returns the tone register value whose output is
+// closest to freq Hz at the given chip clock. Frequencies outside the
+// chip's range are clamped to 2 (highest pitch) or 1023 (lowest); register
+// values 0 and 1 do not produce a tone on every variant.
+func ToneRegForFrequency(clockFreq int, freq float64) uint16 {
+	reg := math.Round(float64(clockFreq) / (32 * freq))
+	return uint16(min(max(reg, 2), 0x3FF))
+}
+
+// Ended reports whether all events have been played.
+func (p *MIDIPlayer) Ended() bool {
+	return p.ended
+}
+
+// TotalClocks returns the chip clock position of the last event, the
+// length of the file.
+func (p *MIDIPlayer) TotalClocks() uint64 {
+	if len(p.events) == 0 {
+		return 0
+	}
+	return p.events[len(p.events)-1].clock
+}
+
+// Chip returns the chip being driven.
+func (p *MIDIPlayer) Chip() *SN76489 {
+	return p.chip
+}
+
+// GenerateSamples resets the chip's buffer and plays the given number of
+// chip clocks. Returns the number of samples dropped due to buffer overflow.
+func (p *MIDIPlayer) GenerateSamples(clocks int) int {
+	p.chip.ResetBuffer()
+	return p.Run(clocks)
+}
+
+// Run plays the given number of chip clocks, accumulating samples into the
+// chip's buffer like SN76489.Run. Returns the number of samples dropped due
+// to buffer overflow.
+func (p *MIDIPlayer) Run(clocks int) int {
+	target := p.clocks + uint64(clocks)
+	dropped := 0
+	for p.pos < len(p.events) {
+		e := &p.events[p.pos]
+		if e.clock > target {
+			break
+		}
+		if e.clock > p.clocks {
+			dropped += p.chip.Run(int(e.clock - p.clocks))
+			p.clocks = e.clock
+		}
+		p.apply(e.MIDIEvent)
+		p.pos++
+	}
+	p.ended = p.pos >= len(p.events)
+	if target > p.clocks {
+		dropped += p.chip.Run(int(target - p.clocks))
+		p.clocks = target
+	}
+	return dropped
+}
+
+// apply executes one MIDI event.
+func (p *MIDIPlayer) apply(e MIDIEvent) {
+	if e.Status >= midiSysEx || len(e.Data) < 2 {
+		return // Meta, SysEx, and program and channel pressure (ignored)
+	}
+	ch := e.Channel()
+	state := &p.channels[ch]
+	switch e.Kind() {
+	case midiNoteOn:
+		if e.Data[1] == 0 {
+			p.noteOff(ch, e.Data[0])
+		} else {
+			p.noteOn(ch, e.Data[0], e.Data[1])
+		}
+	case midiNoteOff:
+		p.noteOff(ch, e.Data[0])
+	case midiPitchBend:
+		state.bend = int(e.Data[0]) | int(e.Data[1])<<7
+		p.refresh(ch)
+	case midiControlChange:
+		switch e.Data[0] {
+		case midiCCVolume:
+			state.volume = e.Data[1]
+			p.refresh(ch)
+		case midiCCExpression:
+			state.expression = e.Data[1]
+			p.refresh(ch)
+		case midiCCAllSoundOff, midiCCAllNotesOff:
+			for v := range p.voices {
+				if p.voices[v].active && p.voices[v].channel == ch {
+					p.noteOff(ch, p.voices[v].note)
+				}
+			}
+			if ch == midiPercussionChannel && p.drumNote >= 0 {
+				p.noteOff(ch, uint8(p.drumNote))
+			}
+		}
+	}
+}
+
+// noteOn starts a note on a free or stolen tone channel, or a drum on the
+// noise channel.
+func (p *MIDIPlayer) noteOn(ch int, note, vel uint8) {
+	if ch == midiPercussionChannel {
+		p.drumNote = int(note)
+		p.chip.Write(EncodeNoise(midiDrumNoise(note)))
+		p.chip.Write(EncodeVolume(3, p.level(ch, vel)))
+		return
+	}
+
+	// Prefer the voice already playing this note, then the free voice
+	// released longest ago, then steal the oldest note.
+	best := -1
+	for v := range p.voices {
+		voice := &p.voices[v]
+		if voice.active && voice.channel == ch && voice.note == note {
+			best = v
+			break
+		}
+		if best < 0 || p.voices[best].active && !voice.active ||
+			p.voices[best].active == voice.active && voice.stamp < p.voices[best].stamp {
+			best = v
+		}
+	}
+	p.stamp++
+	p.voices[best] = midiPlayerVoice{active: true, channel: ch, note: note, vel: vel, stamp: p.stamp}
+	p.tune(best)
+}
+
+// noteOff releases the tone channel or drum playing note on MIDI channel ch.
+func (p *MIDIPlayer) noteOff(ch int, note uint8) {
+	if ch == midiPercussionChannel {
+		if p.drumNote == int(note) {
+			p.drumNote = -1
+			p.chip.Write(EncodeVolume(3, 0x0F))
+		}
+		return
+	}
+	for v := range p.voices {
+		voice := &p.voices[v]
+		if voice.active && voice.channel == ch && voice.note == note {
+			p.stamp++
+			voice.active = false
+			voice.stamp = p.stamp
+			p.chip.Write(EncodeVolume(v, 0x0F))
+		}
+	}
+}
+
+// refresh re-applies pitch and volume to the voices of MIDI channel ch after
+// a controller change.
+func (p *MIDIPlayer) refresh(ch int) {
+	for v := range p.voices {
+		if p.voices[v].active && p.voices[v].channel == ch {
+			p.tune(v)
+		}
+	}
+}
+
+// tune writes the tone register and volume for tone channel v's note.
+func (p *MIDIPlayer) tune(v int) {
+	voice := &p.voices[v]
+	bend := float64(p.channels[voice.channel].bend-midiBendCenter) / midiBendCenter * midiBendSemitones
+	freq := 440 * math.Pow(2, (float64(voice.note)-69+bend)/12)
+	reg := ToneRegForFrequency(p.chip.clockFreq, freq)
+
+	tone := EncodeTone(v, reg)
+	p.chip.Write(tone[0])
+	p.chip.Write(tone[1])
+	p.chip.Write(EncodeVolume(v, p.level(voice.channel, voice.vel)))
+}
+
+// level returns the attenuation level nearest to the amplitude of velocity
+// scaled by MIDI channel ch's volume and expression.
+func (p *MIDIPlayer) level(ch int, vel uint8) uint8 {
+	state := p.channels[ch]
+	amp := float64(vel) / 127 * float64(state.volume) / 127 * float64(state.expression) / 127
+	if amp <= 0 {
+		return 0x0F
+	}
+	level := math.Round(-20 * math.Log10(amp) / 2)
+	return uint8(min(max(level, 0), 14))
+}
+
+// midiDrumNoise returns the noise mode for a General MIDI percussion note.
+func midiDrumNoise(note uint8) (white bool, rate uint8) {
+	switch note {
+	case 35, 36: // Bass drums: low periodic buzz
+		return false, 2
+	case 42, 44, 46, 49, 51, 52, 53, 55, 57, 59: // Hi-hats and cymbals
+		return true, 0
+	case 41, 43, 45, 47, 48, 50: // Toms
+		return true, 2
+	default: // Snares, claps and everything else
+		return true, 1
+	}
+}
@@ -0,0 +1,234 @@
+package sn76489
+
+import "testing"
+
+// midiTestFile builds a format-0 file at 96 ticks per quarter note and
+// 120 BPM, so one tick is 1/192 s.
+func midiTestFile(events ...MIDIEvent) *MIDIFile {
+	return &MIDIFile{Format: 0, Division: 96, Tracks: []MIDITrack{events}}
+}
+
+// TestToneRegForFrequency verifies register values and range clamping.
+func TestToneRegForFrequency(t *testing.T) {
+	tests := []struct {
+		freq float64
+		want uint16
+	}{
+		{440, 254},
+		{110, 1017},
+		{20, 0x3FF},
+		{100000, 2},
+	}
+	for _, tt := range tests {
+		if got := ToneRegForFrequency(3579545, tt.freq); got != tt.want {
+			t.Errorf("%.0f Hz: expected %d, got %d", tt.freq, tt.want, got)
+		}
+	}
+}
+
+// TestMIDIPlayer_Notes verifies notes are written with the right tone
+// register and volume at the right time, and released on note-off.
+func TestMIDIPlayer_Notes(t *testing.T) {
+	f := midiTestFile(
+		midiChannelEvent(0, midiNoteOn, 0, 69, 127),
+		midiChannelEvent(96, midiNoteOff, 0, 69, 0),
+	)
+	chip := New(3579545, 44100, 44100, Sega)
+	p := NewMIDIPlayer(f, chip)
+	if p.TotalClocks() != 3579545/2 {
+		t.Errorf("TotalClocks: expected %d, got %d", 3579545/2, p.TotalClocks())
+	}
+
+	p.Run(1000)
+	info := chip.AnalyzeChannel(0)
+	if chip.GetToneReg(0) != 254 || info.Note != 69 {
+		t.Errorf("Tone: expected reg 254 (A4), got %d (note %d)", chip.GetToneReg(0), info.Note)
+	}
+	if chip.GetVolume(0) != 1 { // Velocity 127 at default channel volume 100: -2.1 dB
+		t.Errorf("Volume: expected 1, got %d", chip.GetVolume(0))
+	}
+
+	p.Run(3579545/2 - 1001)
+	if chip.GetVolume(0) == 0x0F || p.Ended() {
+		t.Fatal("Note-off should not apply before its clock")
+	}
+	p.Run(1)
+	if chip.GetVolume(0) != 0x0F || !p.Ended() {
+		t.Errorf("After note-off: volume=%d ended=%v", chip.GetVolume(0), p.Ended())
+	}
+}
+
+// TestMIDIPlayer_VoiceStealing verifies free voices are used first and a
+// fourth note steals the oldest sounding note.
+func TestMIDIPlayer_VoiceStealing(t *testing.T) {
+	f := midiTestFile(
+		midiChannelEvent(0, midiNoteOn, 0, 60, 127),
+		midiChannelEvent(1, midiNoteOn, 1, 64, 127),
+		midiChannelEvent(2, midiNoteOn, 0, 67, 127),
+		midiChannelEvent(3, midiNoteOn, 0, 72, 127), // Steals 60 on channel 0
+		midiChannelEvent(4, midiNoteOff, 1, 64, 0),  // Frees channel 1
+		midiChannelEvent(5, midiNoteOn, 2, 76, 127), // Uses channel 1
+		midiChannelEvent(6, midiNoteOff, 0, 60, 0),  // Already stolen: ignored
+	)
+	chip := New(3579545, 44100, 800, Sega)
+	p := NewMIDIPlayer(f, chip)
+
+	notes := func() [3]int {
+		var n [3]int
+		for ch := 0; ch < 3; ch++ {
+			if chip.GetVolume(ch) == 0x0F {
+				n[ch] = -1
+			} else {
+				n[ch] = chip.AnalyzeChannel(ch).Note
+			}
+		}
+		return n
+	}
+
+	p.Run(int(p.events[2].clock))
+	if got := notes(); got != [3]int{60, 64, 67} {
+		t.Errorf("Three notes: got %v", got)
+	}
+	p.Run(int(p.events[3].clock - p.events[2].clock))
+	if got := notes(); got != [3]int{72, 64, 67} {
+		t.Errorf("After stealing: got %v", got)
+	}
+	p.Run(int(p.events[4].clock - p.events[3].clock))
+	if got := notes(); got != [3]int{72, -1, 67} {
+		t.Errorf("After note-off: got %v", got)
+	}
+	p.Run(int(p.events[6].clock - p.events[4].clock))
+	if got := notes(); got != [3]int{72, 76, 67} {
+		t.Errorf("Reused voice: got %v", got)
+	}
+}
+
+// TestMIDIPlayer_Drums verifies percussion notes drive the noise channel.
+func TestMIDIPlayer_Drums(t *testing.T) {
+	f := midiTestFile(
+		midiChannelEvent(0, midiNoteOn, 9, 36, 127),
+		midiChannelEvent(10, midiNoteOn, 9, 42, 64),
+		midiChannelEvent(20, midiNoteOff, 9, 36, 0), // Not sounding: ignored
+		midiChannelEvent(30, midiNoteOff, 9, 42, 0),
+	)
+	chip := New(3579545, 44100, 800, Sega)
+	p := NewMIDIPlayer(f, chip)
+
+	p.Run(1)
+	if chip.GetNoiseReg() != 0x02 || chip.GetVolume(3) == 0x0F {
+		t.Errorf("Bass drum: noise=0x%X volume=%d", chip.GetNoiseReg(), chip.GetVolume(3))
+	}
+	p.Run(int(p.events[2].clock))
+	if chip.GetNoiseReg() != 0x04 || chip.GetVolume(3) != 4 { // 64/127 * 100/127: -8 dB
+		t.Errorf("Hi-hat: noise=0x%X volume=%d", chip.GetNoiseReg(), chip.GetVolume(3))
+	}
+	p.Run(int(p.events[3].clock))
+	if chip.GetVolume(3) != 0x0F {
+		t.Errorf("After note-off: volume=%d", chip.GetVolume(3))
+	}
+	for ch := 0; ch < 3; ch++ {
+		if chip.GetVolume(ch) != 0x0F {
+			t.Errorf("Tone channel %d should stay silent", ch)
+		}
+	}
+}
+
+// TestMIDIPlayer_Controllers verifies pitch bend, channel volume and
+// all-notes-off apply to sounding notes.
+func TestMIDIPlayer_Controllers(t *testing.T) {
+	f := midiTestFile(
+		midiChannelEvent(0, midiNoteOn, 3, 69, 127),
+		midiChannelEvent(1, midiPitchBend, 3, 0x00, 0x60), // +1 semitone
+		midiChannelEvent(2, midiControlChange, 3, midiCCVolume, 32),
+		midiChannelEvent(3, midiControlChange, 3, midiCCAllNotesOff, 0),
+	)
+	chip := New(3579545, 44100, 800, Sega)
+	p := NewMIDIPlayer(f, chip)
+
+	p.Run(int(p.events[1].clock))
+	if note := chip.AnalyzeChannel(0).Note; note != 70 {
+		t.Errorf("Pitch bend: expected note 70, got %d", note)
+	}
+	p.Run(int(p.events[2].clock - p.events[1].clock))
+	if v := chip.GetVolume(0); v != 6 { // 32/127: -12 dB
+		t.Errorf("Channel volume: expected level 6, got %d", v)
+	}
+	p.Run(int(p.events[3].clock - p.events[2].clock))
+	if v := chip.GetVolume(0); v != 0x0F {
+		t.Errorf("All notes off: expected silent, got %d", v)
+	}
+}
+
+// TestMIDIPlayer_TempoChange verifies event times follow the tempo map.
+func TestMIDIPlayer_TempoChange(t *testing.T) {
+	f := &MIDIFile{Format: 1, Division: 96, Tracks: []MIDITrack{
+		{midiMetaEvent(96, midiMetaTempo, []byte{0x03, 0xD0, 0x90})}, // 250000 us: 240 BPM
+		{
+			midiChannelEvent(0, midiNoteOn, 0, 60, 100),
+			midiChannelEvent(192, midiNoteOff, 0, 60, 0),
+		},
+	}}
+	p := NewMIDIPlayer(f, New(4000000, 44100, 800, Sega))
+	// One beat at 0.5 s, then one beat at 0.25 s.
+	if got, want := p.TotalClocks(), uint64(3000000); got != want {
+		t.Errorf("TotalClocks: expected %d, got %d", want, got)
+	}
+}
+
+// TestMIDIPlayer_ExportRoundTrip verifies a file exported from chip activity
+// plays back the same notes. Each new note goes to the voice released
+// longest ago, so the notes rotate across channels.
+func TestMIDIPlayer_ExportRoundTrip(t *testing.T) {
+	e := NewMIDIExporter(New(3579545, 44100, 800, Sega))
+	for i, reg := range []uint16{254, 214, 190, 170} {
+		e.Write(uint8(0x80 | reg&0x0F))
+		e.Write(uint8(reg >> 4))
+		e.Write(uint8(0x90 | i))
+		e.GenerateSamples(100000)
+	}
+	data, err := e.File().MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := ParseMIDI(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chip := New(3579545, 44100, 800, Sega)
+	p := NewMIDIPlayer(f, chip)
+	for i, want := range []uint16{254, 214, 190, 170} {
+		p.Run(50000)
+		ch := i % 3
+		if got := chip.GetToneReg(ch); got != want || chip.GetVolume(ch) != uint8(i) {
+			t.Errorf("Note %d: expected tone reg %d volume %d on channel %d, got %d volume %d",
+				i, want, i, ch, got, chip.GetVolume(ch))
+		}
+		p.Run(50000)
+	}
+}
+
+// TestMIDIPlayer_ExportVolumeEnvelope verifies volume changes on a held
+// note survive export and playback at the exact level.
+func TestMIDIPlayer_ExportVolumeEnvelope(t *testing.T) {
+	e := NewMIDIExporter(New(3579545, 44100, 800, Sega))
+	writeTone(e.Chip(), 0, 254)
+	levels := []uint8{8, 4, 1, 6, 9, 14, 11, 2}
+	for _, level := range levels {
+		e.Write(EncodeVolume(0, level))
+		e.GenerateSamples(100000)
+	}
+
+	chip := New(3579545, 44100, 800, Sega)
+	p := NewMIDIPlayer(e.File(), chip)
+	for i, level := range levels {
+		p.Run(50000)
+		// A louder step retriggers the note, which may move it to another
+		// voice; only one voice sounds at a time.
+		got := min(chip.GetVolume(0), chip.GetVolume(1), chip.GetVolume(2))
+		if got != level {
+			t.Errorf("Step %d: expected volume %d, got %d", i, level, got)
+		}
+		p.Run(50000)
+	}
+}
@@ -0,0 +1,48 @@
+package sn76489
+
+import "math/rand/v2"
+
+// SetPowerOnRandomization enables or disables randomized power-on state.
+// Real hardware powers on with undefined register contents, counters and
+// output flip-flops; when enabled, each Reset fills these from a PRNG seeded
+// with seed instead of the zeroed defaults. The same seed always produces the
+// same power-on state, so replays stay reproducible. Takes effect on the next
+// Reset.
+//
+// Randomized: tone registers and counters, tone output flip-flops, noise
+// register, counter, toggle and output, volumes, latch, the clock divider
+// phase, and the LFSR (any nonzero value within the LFSR width). The
+// output sample phase is host resampling state and is always zeroed.
+func (s *SN76489) SetPowerOnRandomization(enabled bool, seed uint64) {
+	s.randomPowerOn = enabled
+	s.powerOnSeed = seed
+}
+
+// GetPowerOnRandomization returns whether randomized power-on is enabled and
+// its seed.
+func (s *SN76489) GetPowerOnRandomization() (enabled bool, seed uint64) {
+	return s.randomPowerOn, s.powerOnSeed
+}
+
+// randomizePowerOn fills the chip state from the power-on seed. All values
+// are within the ranges accepted by State.Validate.
+func (s *SN76489) randomizePowerOn() {
+	rng := rand.New(rand.NewPCG(s.powerOnSeed, 0x5E76489))
+
+	for i := 0; i < 3; i++ {
+		s.toneReg[i] = uint16(rng.IntN(0x400))
+		s.toneCounter[i] = uint16(rng.IntN(0x400))
+		s.toneOutput[i] = rng.IntN(2) == 1
+	}
+	s.noiseReg = uint8(rng.IntN(8))
+	s.noiseCounter = uint16(rng.IntN(0x400))
+	s.noiseToggle = rng.IntN(2) == 1
+	s.noiseOut = rng.IntN(2) == 1
+	s.noiseShift = uint16(1 + rng.IntN(1<<(s.feedbackShift+1)-1))
+	for i := range s.volume {
+		s.volume[i] = uint8(rng.IntN(16))
+	}
+	s.latchedChannel = uint8(rng.IntN(4))
+	s.latchedType = uint8(rng.IntN(2))
+	s.clockDivider = rng.IntN(16)
+}
@@ -0,0 +1,92 @@
+package sn76489
+
+import "testing"
+
+// TestSN76489_PowerOnRandomizationDeterministic verifies the same seed gives
+// the same power-on state and output, and different seeds differ.
+func TestSN76489_PowerOnRandomizationDeterministic(t *testing.T) {
+	a := New(3579545, 48000, 800, Sega)
+	b := New(3579545, 48000, 800, Sega)
+	a.SetPowerOnRandomization(true, 42)
+	b.SetPowerOnRandomization(true, 42)
+	a.Reset()
+	b.Reset()
+	if a.SaveState() != b.SaveState() {
+		t.Fatal("Same seed produced different power-on states")
+	}
+	powerOn := a.SaveState()
+
+	a.GenerateSamples(59659)
+	b.GenerateSamples(59659)
+	if a.StateHashWithOutput() != b.StateHashWithOutput() {
+		t.Error("Same seed produced different output")
+	}
+
+	// A second Reset repeats the same power-on state.
+	a.Reset()
+	if a.SaveState() != powerOn {
+		t.Error("Repeated Reset should give the same power-on state")
+	}
+
+	b.SetPowerOnRandomization(true, 43)
+	b.Reset()
+	if a.SaveState() == b.SaveState() {
+		t.Error("Different seeds produced identical power-on states")
+	}
+
+	defaults := New(3579545, 48000, 800, Sega).SaveState()
+	if a.SaveState() == defaults {
+		t.Error("Randomized state should differ from defaults")
+	}
+}
+
+// TestSN76489_PowerOnRandomizationValid verifies randomized states are always
+// within range and the LFSR is nonzero and fits its width.
+func TestSN76489_PowerOnRandomizationValid(t *testing.T) {
+	for _, cfg := range []Config{Sega, TI} {
+		chip := New(3579545, 48000, 800, cfg)
+		width := uint32(1) << cfg.LFSRBits
+		for seed := uint64(0); seed < 500; seed++ {
+			chip.SetPowerOnRandomization(true, seed)
+			chip.Reset()
+			st := chip.SaveState()
+			if err := chip.validateState(&st); err != nil {
+				t.Fatalf("Seed %d: %v", seed, err)
+			}
+			if st.NoiseShift == 0 || uint32(st.NoiseShift) >= width {
+				t.Fatalf("Seed %d: LFSR 0x%X out of range", seed, st.NoiseShift)
+			}
+			if st.ClockCounter != 0 {
+				t.Fatalf("Seed %d: clock counter should be zeroed", seed)
+			}
+		}
+	}
+}
+
+// TestSN76489_PowerOnRandomizationDisable verifies disabling restores the
+// zeroed power-on defaults.
+func TestSN76489_PowerOnRandomizationDisable(t *testing.T) {
+	chip := New(3579545, 48000, 800, TI)
+	defaults := chip.SaveState()
+
+	chip.SetPowerOnRandomization(true, 7)
+	if enabled, seed := chip.GetPowerOnRandomization(); !enabled || seed != 7 {
+		t.Errorf("GetPowerOnRandomization: got %v, %d", enabled, seed)
+	}
+	if chip.SaveState() != defaults {
+		t.Error("Enabling should not take effect until Reset")
+	}
+	chip.Reset()
+	if chip.SaveState() == defaults {
+		t.Error("Reset should randomize when enabled")
+	}
+	if enabled, seed := chip.Clone().GetPowerOnRandomization(); !enabled || seed != 7 {
+		t.Error("Clone should copy the power-on randomization setting")
+	}
+
+	chip.SetPowerOnRandomization(false, 0)
+	chip.Reset()
+	if chip.SaveState() != defaults {
+		t.Error("Reset should restore defaults when disabled")
+	}
+}
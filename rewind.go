@@ -0,0 +1,164 @@
+package sn76489
+
+// Rewind is a bounded history of chip snapshots for rewinding. Each snapshot
+// is stored as a sparse delta (changed byte offsets and values) against the
+// most recent keyframe, a full Serialize state taken every keyframeInterval
+// pushes. Most PSG state is unchanged frame to frame, so a delta is usually a
+// handful of bytes.
+//
+// Frame numbers are supplied by the caller, so a Rewind can be kept in step
+// with an emulator's own rewind system.
+type Rewind struct {
+	entries []rewindEntry // Ring buffer, oldest at head
+	head    int
+	count   int
+
+	keyframeInterval int
+	sinceKeyframe    int
+	keyframe         []byte // Base for new deltas, shared with entries
+	scratch          []byte // Reused serialize/restore buffer
+}
+
+// rewindEntry is one snapshot: a delta against a keyframe. Keyframe entries
+// have an empty delta.
+type rewindEntry struct {
+	frame uint64
+	clock uint64 // Chip clock timestamp (see GetClock)
+	base  []byte // Full state this entry is relative to
+	delta []byte // Offset/value pairs where the state differs from base
+}
+
+// NewRewind creates a history holding at most capacity snapshots, with a
+// keyframe every keyframeInterval snapshots. When full, the oldest snapshot
+// is discarded.
+func NewRewind(capacity int, keyframeInterval int) *Rewind {
+	if capacity < 1 {
+		capacity = 1
+	}
+	if keyframeInterval < 1 {
+		keyframeInterval = 1
+	}
+	return &Rewind{
+		entries:          make([]rewindEntry, capacity),
+		keyframeInterval: keyframeInterval,
+		scratch:          make([]byte, SerializeSize),
+	}
+}
+
+// Len returns the number of stored snapshots.
+func (r *Rewind) Len() int {
+	return r.count
+}
+
+// Clear discards all snapshots.
+func (r *Rewind) Clear() {
+	r.head = 0
+	r.count = 0
+	r.sinceKeyframe = 0
+	r.keyframe = nil
+}
+
+// Frames returns the frame numbers of the oldest and newest snapshots.
+// ok is false if the history is empty.
+func (r *Rewind) Frames() (oldest, newest uint64, ok bool) {
+	if r.count == 0 {
+		return 0, 0, false
+	}
+	return r.at(0).frame, r.at(r.count - 1).frame, true
+}
+
+// at returns the i-th oldest entry.
+func (r *Rewind) at(i int) *rewindEntry {
+	return &r.entries[(r.head+i)%len(r.entries)]
+}
+
+// Push records the chip's state for frame. Snapshots at or after frame are
+// discarded first, so pushing after a Seek or Pop starts a new timeline.
+func (r *Rewind) Push(frame uint64, chip *SN76489) {
+	r.truncate(frame)
+	chip.Serialize(r.scratch)
+
+	if r.count == len(r.entries) {
+		r.head = (r.head + 1) % len(r.entries)
+		r.count--
+	}
+	e := r.at(r.count)
+	r.count++
+	e.frame = frame
+	e.clock = chip.clocks
+	e.delta = e.delta[:0]
+
+	if r.keyframe != nil && r.sinceKeyframe < r.keyframeInterval {
+		for i, b := range r.scratch {
+			if b != r.keyframe[i] {
+				e.delta = append(e.delta, uint8(i), b)
+			}
+		}
+		if len(e.delta) < SerializeSize {
+			e.base = r.keyframe
+			r.sinceKeyframe++
+			return
+		}
+		e.delta = e.delta[:0]
+	}
+
+	// New keyframe. Entries relative to the previous keyframe keep their
+	// reference to it, so it is never modified in place.
+	r.keyframe = append([]byte(nil), r.scratch...)
+	e.base = r.keyframe
+	r.sinceKeyframe = 1
+}
+
+// Pop restores the newest snapshot into chip and removes it. Returns the
+// snapshot's frame, or ok false if the history is empty.
+func (r *Rewind) Pop(chip *SN76489) (frame uint64, ok bool) {
+	if r.count == 0 {
+		return 0, false
+	}
+	e := r.at(r.count - 1)
+	if err := r.restore(e, chip); err != nil {
+		return 0, false
+	}
+	r.count--
+	return e.frame, true
+}
+
+// Seek restores the newest snapshot at or before frame into chip and
+// discards all snapshots after it. The restored snapshot is kept. Returns
+// the restored frame, or ok false if no snapshot is that old.
+func (r *Rewind) Seek(frame uint64, chip *SN76489) (restored uint64, ok bool) {
+	i := r.count - 1
+	for i >= 0 && r.at(i).frame > frame {
+		i--
+	}
+	if i < 0 {
+		return 0, false
+	}
+	e := r.at(i)
+	if err := r.restore(e, chip); err != nil {
+		return 0, false
+	}
+	r.count = i + 1
+	return e.frame, true
+}
+
+// truncate discards snapshots at or after frame.
+func (r *Rewind) truncate(frame uint64) {
+	for r.count > 0 && r.at(r.count-1).frame >= frame {
+		r.count--
+	}
+}
+
+// restore rebuilds e's full state and loads it into chip, along with its
+// clock timestamp.
+func (r *Rewind) restore(e *rewindEntry, chip *SN76489) error {
+	copy(r.scratch, e.base)
+	for i := 0; i+1 < len(e.delta); i += 2 {
+		r.scratch[e.delta[i]] = e.delta[i+1]
+	}
+	if err := chip.Deserialize(r.scratch); err != nil {
+		return err
+	}
+	chip.clocks = e.clock
+	return nil
+}
@@ -0,0 +1,133 @@
+package sn76489
+
+import "testing"
+
+// rewindFrame advances chip by one frame with a write that varies per frame.
+func rewindFrame(chip *SN76489, frame int) {
+	chip.Write(uint8(0x80 | frame&0x0F))
+	chip.Write(uint8(frame & 0x3F))
+	chip.Write(uint8(0x90 | frame%15))
+	chip.GenerateSamples(59659)
+}
+
+// TestRewind_PopRestoresInOrder verifies popping returns snapshots newest
+// first with the exact state that was pushed.
+func TestRewind_PopRestoresInOrder(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	chip.Write(0xE4)
+	rw := NewRewind(100, 10)
+
+	var states []State
+	for frame := 0; frame < 50; frame++ {
+		rewindFrame(chip, frame)
+		rw.Push(uint64(frame), chip)
+		states = append(states, chip.SaveState())
+	}
+
+	target := New(3579545, 48000, 800, Sega)
+	for frame := 49; frame >= 0; frame-- {
+		got, ok := rw.Pop(target)
+		if !ok || got != uint64(frame) {
+			t.Fatalf("Pop: expected frame %d, got %d (ok=%v)", frame, got, ok)
+		}
+		if target.SaveState() != states[frame] {
+			t.Fatalf("Frame %d: restored state mismatch", frame)
+		}
+	}
+	if _, ok := rw.Pop(target); ok {
+		t.Error("Pop on empty history should fail")
+	}
+}
+
+// TestRewind_Seek verifies seeking restores the nearest earlier frame and
+// discards later ones, and that pushing afterwards continues from there.
+func TestRewind_Seek(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	rw := NewRewind(100, 8)
+
+	states := map[uint64]State{}
+	for frame := uint64(0); frame < 60; frame += 2 {
+		rewindFrame(chip, int(frame))
+		rw.Push(frame, chip)
+		states[frame] = chip.SaveState()
+	}
+
+	got, ok := rw.Seek(31, chip)
+	if !ok || got != 30 {
+		t.Fatalf("Seek(31): expected frame 30, got %d (ok=%v)", got, ok)
+	}
+	if chip.SaveState() != states[30] {
+		t.Error("Seek restored the wrong state")
+	}
+	if _, newest, _ := rw.Frames(); newest != 30 {
+		t.Errorf("Newest after Seek: expected 30, got %d", newest)
+	}
+
+	// Continue on a new timeline; the old frames 32+ must not reappear.
+	rewindFrame(chip, 99)
+	rw.Push(31, chip)
+	branch := chip.SaveState()
+	if got, ok := rw.Pop(chip); !ok || got != 31 || chip.SaveState() != branch {
+		t.Errorf("Pop after branch: frame=%d ok=%v", got, ok)
+	}
+	if got, ok := rw.Pop(chip); !ok || got != 30 || chip.SaveState() != states[30] {
+		t.Errorf("Pop before branch: frame=%d ok=%v", got, ok)
+	}
+
+	if _, ok := NewRewind(4, 2).Seek(0, chip); ok {
+		t.Error("Seek on empty history should fail")
+	}
+}
+
+// TestRewind_BoundedCapacity verifies the oldest snapshots are evicted and
+// the remaining ones, including deltas whose keyframe was evicted, restore
+// correctly.
+func TestRewind_BoundedCapacity(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	rw := NewRewind(16, 5)
+
+	var states []State
+	for frame := 0; frame < 100; frame++ {
+		rewindFrame(chip, frame)
+		rw.Push(uint64(frame), chip)
+		states = append(states, chip.SaveState())
+	}
+
+	if rw.Len() != 16 {
+		t.Fatalf("Len: expected 16, got %d", rw.Len())
+	}
+	oldest, newest, ok := rw.Frames()
+	if !ok || oldest != 84 || newest != 99 {
+		t.Fatalf("Frames: expected 84-99, got %d-%d", oldest, newest)
+	}
+	if _, ok := rw.Seek(83, chip); ok {
+		t.Error("Seek before the oldest snapshot should fail")
+	}
+
+	got, ok := rw.Seek(84, chip)
+	if !ok || got != 84 || chip.SaveState() != states[84] {
+		t.Errorf("Seek(84): frame=%d ok=%v", got, ok)
+	}
+}
+
+// TestRewind_DeltasAreSmall verifies unchanged state is stored compactly.
+func TestRewind_DeltasAreSmall(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	rw := NewRewind(10, 10)
+	for frame := uint64(0); frame < 10; frame++ {
+		rw.Push(frame, chip)
+	}
+	for i := 1; i < rw.Len(); i++ {
+		if n := len(rw.at(i).delta); n != 0 {
+			t.Errorf("Entry %d: identical state should have an empty delta, got %d bytes", i, n)
+		}
+	}
+
+	rw.Clear()
+	if rw.Len() != 0 {
+		t.Errorf("Len after Clear: expected 0, got %d", rw.Len())
+	}
+	if _, _, ok := rw.Frames(); ok {
+		t.Error("Frames after Clear should report empty")
+	}
+}
@@ -0,0 +1,85 @@
+package sn76489
+
+// ScopeWindow is one channel's oscilloscope view of the current frame.
+type ScopeWindow struct {
+	Samples   []float32 // Slice of the channel buffer; valid until the next Run
+	Start     int       // Sample index of Samples[0] in the frame
+	Triggered bool      // Start is a trigger point; false = last samples of the frame
+}
+
+// SetScopeRecording enables or disables scope trigger recording. While
+// enabled, Run records the sample index of each channel's trigger points in
+// the current frame, and ResetBuffer (including GenerateSamples) clears
+// them. Tone channels trigger on every rising edge of the output. White
+// noise triggers on rising edges of the noise output; periodic noise
+// triggers once per pattern, when the LFSR returns to its reset value.
+func (s *SN76489) SetScopeRecording(enabled bool) {
+	s.scopeOn = enabled
+	for ch := range s.scopeTriggers {
+		s.scopeTriggers[ch] = s.scopeTriggers[ch][:0]
+	}
+}
+
+// GetScopeRecording reports whether scope trigger recording is enabled.
+func (s *SN76489) GetScopeRecording() bool {
+	return s.scopeOn
+}
+
+// ScopeTriggers returns the sample indices of the trigger points recorded
+// for channel ch (0-3) in the current frame, in order. The slice is reused
+// and is only valid until the next ResetBuffer.
+func (s *SN76489) ScopeTriggers(ch int) []int {
+	return s.scopeTriggers[ch]
+}
+
+// ScopeWindows returns a width-sample window of each channel buffer,
+// starting at the latest trigger point that leaves room for the full width,
+// so the waveform stays still from frame to frame. A channel with no such
+// trigger (silent, constant output, recording disabled or a frame shorter
+// than width) gets the last width samples instead. Windows are shorter than
+// width only when the frame is.
+//
+//	chip.SetScopeRecording(true)
+//	chip.GenerateSamples(clocksPerFrame)
+//	for ch, w := range chip.ScopeWindows(256) {
+//		ui.DrawScope(ch, w.Samples)
+//	}
+func (s *SN76489) ScopeWindows(width int) [4]ScopeWindow {
+	var windows [4]ScopeWindow
+	n := s.bufferPos
+	width = max(0, min(width, n))
+	for ch := range windows {
+		w := ScopeWindow{Start: n - width}
+		triggers := s.scopeTriggers[ch]
+		for i := len(triggers) - 1; i >= 0; i-- {
+			if triggers[i]+width <= n {
+				w.Start = triggers[i]
+				w.Triggered = true
+				break
+			}
+		}
+		w.Samples = s.channelBuffers[ch][w.Start : w.Start+width]
+		windows[ch] = w
+	}
+	return windows
+}
+
+// scopeTrigger records a trigger point for channel ch at the current sample
+// index, if it is within the buffer.
+func (s *SN76489) scopeTrigger(ch int) {
+	if s.bufferPos < len(s.mixBuffer) {
+		s.scopeTriggers[ch] = append(s.scopeTriggers[ch], s.bufferPos)
+	}
+}
+
+// scopeNoiseTrigger checks for a noise trigger after an LFSR shift, given
+// the noise output before it.
+func (s *SN76489) scopeNoiseTrigger(prevOut bool) {
+	if s.noiseReg&0x04 != 0 {
+		if s.noiseOut && !prevOut {
+			s.scopeTrigger(3)
+		}
+	} else if s.noiseShift == s.lfsrInitial {
+		s.scopeTrigger(3)
+	}
+}
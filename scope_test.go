@@ -0,0 +1,117 @@
+package sn76489
+
+import "testing"
+
+// scopeTestChip returns a chip with one internal tick per output sample.
+func scopeTestChip(bufferSize int) *SN76489 {
+	return New(16*48000, 48000, bufferSize, Sega)
+}
+
+// TestSN76489_ScopeToneTriggers verifies tone triggers land on rising edges
+// one period apart, and the window starts at the latest full-width trigger.
+func TestSN76489_ScopeToneTriggers(t *testing.T) {
+	chip := scopeTestChip(800)
+	chip.SetScopeRecording(true)
+	writeTone(chip, 0, 10)
+	chip.Write(0x90)
+	chip.GenerateSamples(800 * 16)
+
+	// The first tick toggles the output high, then every 20 ticks.
+	triggers := chip.ScopeTriggers(0)
+	if len(triggers) != 40 {
+		t.Fatalf("Expected 40 triggers, got %d", len(triggers))
+	}
+	for i, tr := range triggers {
+		if tr != 20*i {
+			t.Fatalf("Trigger %d: expected sample %d, got %d", i, 20*i, tr)
+		}
+	}
+
+	w := chip.ScopeWindows(50)[0]
+	if !w.Triggered || w.Start != 740 || len(w.Samples) != 50 {
+		t.Fatalf("Window: expected triggered at 740 with 50 samples, got %+v", w)
+	}
+	if w.Samples[0] == 0 || w.Samples[9] == 0 || w.Samples[10] != 0 || w.Samples[20] == 0 {
+		t.Errorf("Window should start on a high half-period: %v", w.Samples[:21])
+	}
+}
+
+// TestSN76489_ScopePeriodicNoise verifies periodic noise triggers once per
+// LFSR pattern.
+func TestSN76489_ScopePeriodicNoise(t *testing.T) {
+	chip := scopeTestChip(2000)
+	chip.SetScopeRecording(true)
+	chip.Write(0xE0) // Periodic, rate 0
+	chip.Write(0xF0)
+	chip.GenerateSamples(2000 * 16)
+
+	// The LFSR shifts every 32 ticks and the 16-bit pattern repeats every
+	// 16 shifts. The set bit reaches the output on the 16th shift.
+	want := []int{480, 992, 1504}
+	triggers := chip.ScopeTriggers(3)
+	if len(triggers) != len(want) {
+		t.Fatalf("Expected triggers at %v, got %v", want, triggers)
+	}
+	for i := range want {
+		if triggers[i] != want[i] {
+			t.Errorf("Trigger %d: expected %d, got %d", i, want[i], triggers[i])
+		}
+	}
+
+	w := chip.ScopeWindows(100)[3]
+	if !w.Triggered || w.Start != 1504 || w.Samples[0] == 0 || w.Samples[32] != 0 {
+		t.Errorf("Window: expected triggered at 1504 on a high pulse, got start %d", w.Start)
+	}
+}
+
+// TestSN76489_ScopeWhiteNoise verifies white noise triggers on rising edges
+// of the output.
+func TestSN76489_ScopeWhiteNoise(t *testing.T) {
+	chip := scopeTestChip(2000)
+	chip.SetScopeRecording(true)
+	chip.Write(0xE4)
+	chip.Write(0xF0)
+	chip.GenerateSamples(2000 * 16)
+
+	bufs, _ := chip.GetChannelBuffers()
+	triggers := chip.ScopeTriggers(3)
+	if len(triggers) == 0 {
+		t.Fatal("Expected white noise triggers")
+	}
+	for _, tr := range triggers {
+		if bufs[3][tr] == 0 || (tr > 0 && bufs[3][tr-1] != 0) {
+			t.Errorf("Trigger at %d is not a rising edge", tr)
+		}
+	}
+}
+
+// TestSN76489_ScopeUntriggered verifies channels without triggers get the
+// end of the frame, and triggers are cleared per frame.
+func TestSN76489_ScopeUntriggered(t *testing.T) {
+	chip := scopeTestChip(800)
+	chip.SetScopeRecording(true)
+	writeTone(chip, 1, 1) // Constant output
+	chip.GenerateSamples(500 * 16)
+
+	windows := chip.ScopeWindows(100)
+	if w := windows[1]; w.Triggered || w.Start != 400 || len(w.Samples) != 100 {
+		t.Errorf("Constant channel: expected untriggered at 400, got %+v", w)
+	}
+	if w := chip.ScopeWindows(1000)[0]; w.Start != 0 || len(w.Samples) != 500 {
+		t.Errorf("Wide window: expected whole frame, got start %d len %d", w.Start, len(w.Samples))
+	}
+
+	chip.ResetBuffer()
+	for ch := 0; ch < 4; ch++ {
+		if n := len(chip.ScopeTriggers(ch)); n != 0 {
+			t.Errorf("Channel %d: %d triggers after ResetBuffer", ch, n)
+		}
+	}
+
+	chip.SetScopeRecording(false)
+	writeTone(chip, 0, 10)
+	chip.Run(100 * 16)
+	if len(chip.ScopeTriggers(0)) != 0 {
+		t.Error("Triggers recorded while disabled")
+	}
+}
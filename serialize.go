@@ -3,6 +3,7 @@ package sn76489
 import (
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"math"
 )
 
@@ -11,76 +12,305 @@ const serializeVersion = 2
 // SerializeSize is the number of bytes needed to serialize the chip state.
 const SerializeSize = 41
 
+// Errors returned by Serialize and Deserialize. Range errors wrap
+// ErrInvalidState with the offending field.
+var (
+	ErrBufferTooSmall     = errors.New("sn76489: buffer too small")
+	ErrUnsupportedVersion = errors.New("sn76489: unsupported serialize version")
+	ErrInvalidState       = errors.New("sn76489: invalid state")
+)
+
+// State is a snapshot of all mutable chip state, as captured by SaveState.
+// Variant-derived constants and audio config (gain, clock, sample rate,
+// buffers) are not included — the caller handles those via the New
+// constructor and SetGain.
+type State struct {
+	ToneReg     [3]uint16 `json:"toneReg"`     // 10-bit tone registers
+	ToneCounter [3]uint16 `json:"toneCounter"` // Tone counters
+	ToneOutput  [3]bool   `json:"toneOutput"`  // Tone output flip-flops
+
+	NoiseReg     uint8  `json:"noiseReg"`     // 3-bit noise control register
+	NoiseCounter uint16 `json:"noiseCounter"` // Noise counter
+	NoiseShift   uint16 `json:"noiseShift"`   // LFSR
+	NoiseToggle  bool   `json:"noiseToggle"`  // Noise internal toggle
+	NoiseOut     bool   `json:"noiseOut"`     // Noise audio output
+
+	Volume [4]uint8 `json:"volume"` // 4-bit volumes, 0-2 = tone channels, 3 = noise
+
+	LatchedChannel uint8 `json:"latchedChannel"` // Latched channel (0-3)
+	LatchedType    uint8 `json:"latchedType"`    // Latched register type (0 = tone/noise, 1 = volume)
+
+	ClockDivider int     `json:"clockDivider"` // Input clock divider phase (0-15)
+	ClockCounter float64 `json:"clockCounter"` // Fractional clocks toward the next output sample
+}
+
+// SaveState returns a snapshot of all mutable chip state.
+func (s *SN76489) SaveState() State {
+	return State{
+		ToneReg:        s.toneReg,
+		ToneCounter:    s.toneCounter,
+		ToneOutput:     s.toneOutput,
+		NoiseReg:       s.noiseReg,
+		NoiseCounter:   s.noiseCounter,
+		NoiseShift:     s.noiseShift,
+		NoiseToggle:    s.noiseToggle,
+		NoiseOut:       s.noiseOut,
+		Volume:         s.volume,
+		LatchedChannel: s.latchedChannel,
+		LatchedType:    s.latchedType,
+		ClockDivider:   s.clockDivider,
+		ClockCounter:   s.clockCounter,
+	}
+}
+
+// Validate checks that every field is within the range the chip can produce.
+// Loading an out-of-range state (for example LatchedChannel 7 or Volume 200)
+// would make later Write, Sample or Run calls index out of bounds.
+func (st *State) Validate() error {
+	for i := 0; i < 3; i++ {
+		if st.ToneReg[i] > 0x3FF {
+			return fmt.Errorf("%w: tone register %d = 0x%X", ErrInvalidState, i, st.ToneReg[i])
+		}
+		if st.ToneCounter[i] > 0x400 {
+			return fmt.Errorf("%w: tone counter %d = 0x%X", ErrInvalidState, i, st.ToneCounter[i])
+		}
+	}
+	if st.NoiseReg > 0x07 {
+		return fmt.Errorf("%w: noise register = 0x%X", ErrInvalidState, st.NoiseReg)
+	}
+	if st.NoiseCounter > 0x400 {
+		return fmt.Errorf("%w: noise counter = 0x%X", ErrInvalidState, st.NoiseCounter)
+	}
+	for i := 0; i < 4; i++ {
+		if st.Volume[i] > 0x0F {
+			return fmt.Errorf("%w: volume %d = %d", ErrInvalidState, i, st.Volume[i])
+		}
+	}
+	if st.LatchedChannel > 3 {
+		return fmt.Errorf("%w: latched channel = %d", ErrInvalidState, st.LatchedChannel)
+	}
+	if st.LatchedType > 1 {
+		return fmt.Errorf("%w: latched type = %d", ErrInvalidState, st.LatchedType)
+	}
+	if st.ClockDivider < 0 || st.ClockDivider > 15 {
+		return fmt.Errorf("%w: clock divider = %d", ErrInvalidState, st.ClockDivider)
+	}
+	if math.IsNaN(st.ClockCounter) || math.IsInf(st.ClockCounter, 0) || st.ClockCounter < 0 {
+		return fmt.Errorf("%w: clock counter = %v", ErrInvalidState, st.ClockCounter)
+	}
+	return nil
+}
+
+// LoadState restores chip state from a snapshot taken by SaveState. The
+// buffer position is reset, as after Deserialize. Variant-derived constants
+// and audio config are not modified. st must be valid (see State.Validate);
+// states from untrusted sources should be checked first.
+func (s *SN76489) LoadState(st State) {
+	s.toneReg = st.ToneReg
+	s.toneCounter = st.ToneCounter
+	s.toneOutput = st.ToneOutput
+	s.noiseReg = st.NoiseReg
+	s.noiseCounter = st.NoiseCounter
+	s.noiseShift = st.NoiseShift
+	s.noiseToggle = st.NoiseToggle
+	s.noiseOut = st.NoiseOut
+	s.volume = st.Volume
+	s.latchedChannel = st.LatchedChannel
+	s.latchedType = st.LatchedType
+	s.clockDivider = st.ClockDivider
+	s.clockCounter = st.ClockCounter
+	s.ResetBuffer()
+}
+
+// CopyStateFrom copies all mutable chip state from other without
+// allocating, for run-ahead and rollback. Each chip keeps its own output
+// buffers, buffer position, gain and variant constants; other must have been
+// created with the same Config. The clock timestamp (see GetClock) is copied
+// too, so a rollback also rolls back event, trace and breakpoint times.
+func (s *SN76489) CopyStateFrom(other *SN76489) {
+	s.toneReg = other.toneReg
+	s.toneCounter = other.toneCounter
+	s.toneOutput = other.toneOutput
+	s.noiseReg = other.noiseReg
+	s.noiseCounter = other.noiseCounter
+	s.noiseShift = other.noiseShift
+	s.noiseToggle = other.noiseToggle
+	s.noiseOut = other.noiseOut
+	s.volume = other.volume
+	s.latchedChannel = other.latchedChannel
+	s.latchedType = other.latchedType
+	s.clockDivider = other.clockDivider
+	s.clockCounter = other.clockCounter
+	s.clocks = other.clocks
+}
+
+// Clone returns an independent copy of the chip with its own output buffers.
+// Chip state, the clock timestamp, gain, power-on randomization, buffer
+// contents and buffer position are all copied.
+func (s *SN76489) Clone() *SN76489 {
+	c := New(s.clockFreq, s.sampleRate, len(s.mixBuffer), s.config)
+	c.CopyStateFrom(s)
+	c.gain = s.gain
+	c.randomPowerOn = s.randomPowerOn
+	c.powerOnSeed = s.powerOnSeed
+	for ch := range s.channelBuffers {
+		copy(c.channelBuffers[ch], s.channelBuffers[ch])
+	}
+	copy(c.mixBuffer, s.mixBuffer)
+	c.bufferPos = s.bufferPos
+	return c
+}
+
 // Serialize writes all mutable chip state into buf in a compact little-endian
 // binary format. Returns an error if len(buf) < SerializeSize. Variant-derived
 // constants and audio config are not included — the caller handles those via
 // the New constructor and SetGain.
 func (s *SN76489) Serialize(buf []byte) error {
 	if len(buf) < SerializeSize {
-		return errors.New("sn76489: serialize buffer too small")
+		return ErrBufferTooSmall
 	}
+	st := s.SaveState()
+	st.encode(buf)
+	return nil
+}
 
+// Deserialize restores all mutable chip state from buf, which must have been
+// produced by Serialize from this or an older version of the package. Older
+// formats are migrated to the current layout. Returns ErrBufferTooSmall,
+// ErrUnsupportedVersion, or an error wrapping ErrInvalidState if any field is
+// out of range; the chip is left unchanged on error. Variant-derived
+// constants and audio config are not modified — the caller handles those via
+// the New constructor and SetGain.
+func (s *SN76489) Deserialize(buf []byte) error {
+	buf, err := migrateState(buf)
+	if err != nil {
+		return err
+	}
+	for _, off := range []int{13, 14, 15, 21, 40} {
+		if buf[off] > 1 {
+			return fmt.Errorf("%w: flag byte %d = %d", ErrInvalidState, off, buf[off])
+		}
+	}
+	st := decodeState(buf)
+	if err := s.validateState(&st); err != nil {
+		return err
+	}
+	s.LoadState(st)
+	return nil
+}
+
+// serializeSizes is the buffer size of each supported serialize version.
+var serializeSizes = map[uint8]int{
+	1: 40,
+	2: SerializeSize,
+}
+
+// migrations upgrade a buffer of the keyed version to the next version. Each
+// is given a buffer of at least serializeSizes[version] bytes.
+var migrations = map[uint8]func(old []byte) []byte{
+	1: migrateV1,
+}
+
+// migrateState checks the version and size of buf and upgrades it to the
+// current layout. Current-version buffers are returned as-is.
+func migrateState(buf []byte) ([]byte, error) {
+	if len(buf) < 1 {
+		return nil, ErrBufferTooSmall
+	}
+	size, ok := serializeSizes[buf[0]]
+	if !ok {
+		return nil, ErrUnsupportedVersion
+	}
+	if len(buf) < size {
+		return nil, ErrBufferTooSmall
+	}
+	for buf[0] != serializeVersion {
+		buf = migrations[buf[0]](buf)
+	}
+	return buf, nil
+}
+
+// migrateV1 upgrades version 1, which predates the latched noise output
+// (byte 40). Version 1 played the LFSR's low bit directly, so noiseOut is
+// taken from it.
+func migrateV1(old []byte) []byte {
+	buf := make([]byte, serializeSizes[2])
+	copy(buf, old[:serializeSizes[1]])
+	buf[0] = 2
+	buf[40] = old[19] & 1
+	return buf
+}
+
+// validateState checks st against the generic ranges and this chip's LFSR
+// width. A custom LFSRInit wider than the LFSR only ever shifts down, so
+// values up to its top bit are allowed too.
+func (s *SN76489) validateState(st *State) error {
+	if err := st.Validate(); err != nil {
+		return err
+	}
+	limit := uint32(1) << (s.feedbackShift + 1)
+	for limit <= uint32(s.lfsrInitial) {
+		limit <<= 1
+	}
+	if uint32(st.NoiseShift) >= limit {
+		return fmt.Errorf("%w: LFSR = 0x%04X exceeds %d bits", ErrInvalidState, st.NoiseShift, s.feedbackShift+1)
+	}
+	return nil
+}
+
+// encode writes st into buf in the Serialize format. buf must be at least
+// SerializeSize bytes.
+func (st *State) encode(buf []byte) {
 	buf[0] = serializeVersion
 	for i := 0; i < 3; i++ {
-		binary.LittleEndian.PutUint16(buf[1+i*2:], s.toneReg[i])
+		binary.LittleEndian.PutUint16(buf[1+i*2:], st.ToneReg[i])
 	}
 	for i := 0; i < 3; i++ {
-		binary.LittleEndian.PutUint16(buf[7+i*2:], s.toneCounter[i])
+		binary.LittleEndian.PutUint16(buf[7+i*2:], st.ToneCounter[i])
 	}
 	for i := 0; i < 3; i++ {
-		buf[13+i] = boolByte(s.toneOutput[i])
+		buf[13+i] = boolByte(st.ToneOutput[i])
 	}
-	buf[16] = s.noiseReg
-	binary.LittleEndian.PutUint16(buf[17:], s.noiseCounter)
-	binary.LittleEndian.PutUint16(buf[19:], s.noiseShift)
-	buf[21] = boolByte(s.noiseToggle)
+	buf[16] = st.NoiseReg
+	binary.LittleEndian.PutUint16(buf[17:], st.NoiseCounter)
+	binary.LittleEndian.PutUint16(buf[19:], st.NoiseShift)
+	buf[21] = boolByte(st.NoiseToggle)
 	for i := 0; i < 4; i++ {
-		buf[22+i] = s.volume[i]
+		buf[22+i] = st.Volume[i]
 	}
-	buf[26] = s.latchedChannel
-	buf[27] = s.latchedType
-	binary.LittleEndian.PutUint32(buf[28:], uint32(int32(s.clockDivider)))
-	binary.LittleEndian.PutUint64(buf[32:], math.Float64bits(s.clockCounter))
-	buf[40] = boolByte(s.noiseOut)
-	return nil
+	buf[26] = st.LatchedChannel
+	buf[27] = st.LatchedType
+	binary.LittleEndian.PutUint32(buf[28:], uint32(int32(st.ClockDivider)))
+	binary.LittleEndian.PutUint64(buf[32:], math.Float64bits(st.ClockCounter))
+	buf[40] = boolByte(st.NoiseOut)
 }
 
-// Deserialize restores all mutable chip state from buf, which must have been
-// produced by Serialize. Returns an error if the buffer is too small or was
-// produced by an incompatible version. Variant-derived constants and audio
-// config are not modified — the caller handles those via the New constructor
-// and SetGain.
-func (s *SN76489) Deserialize(buf []byte) error {
-	if len(buf) < SerializeSize {
-		return errors.New("sn76489: deserialize buffer too small")
-	}
-	if buf[0] != serializeVersion {
-		return errors.New("sn76489: unsupported serialize version")
-	}
-
+// decodeState reads a State from buf in the Serialize format. buf must be
+// at least SerializeSize bytes.
+func decodeState(buf []byte) State {
+	var st State
 	for i := 0; i < 3; i++ {
-		s.toneReg[i] = binary.LittleEndian.Uint16(buf[1+i*2:])
+		st.ToneReg[i] = binary.LittleEndian.Uint16(buf[1+i*2:])
 	}
 	for i := 0; i < 3; i++ {
-		s.toneCounter[i] = binary.LittleEndian.Uint16(buf[7+i*2:])
+		st.ToneCounter[i] = binary.LittleEndian.Uint16(buf[7+i*2:])
 	}
 	for i := 0; i < 3; i++ {
-		s.toneOutput[i] = buf[13+i] != 0
+		st.ToneOutput[i] = buf[13+i] != 0
 	}
-	s.noiseReg = buf[16]
-	s.noiseCounter = binary.LittleEndian.Uint16(buf[17:])
-	s.noiseShift = binary.LittleEndian.Uint16(buf[19:])
-	s.noiseToggle = buf[21] != 0
+	st.NoiseReg = buf[16]
+	st.NoiseCounter = binary.LittleEndian.Uint16(buf[17:])
+	st.NoiseShift = binary.LittleEndian.Uint16(buf[19:])
+	st.NoiseToggle = buf[21] != 0
 	for i := 0; i < 4; i++ {
-		s.volume[i] = buf[22+i]
-	}
-	s.latchedChannel = buf[26]
-	s.latchedType = buf[27]
-	s.clockDivider = int(int32(binary.LittleEndian.Uint32(buf[28:])))
-	s.clockCounter = math.Float64frombits(binary.LittleEndian.Uint64(buf[32:]))
-	s.noiseOut = buf[40] != 0
-	s.bufferPos = 0
-	return nil
+		st.Volume[i] = buf[22+i]
+	}
+	st.LatchedChannel = buf[26]
+	st.LatchedType = buf[27]
+	st.ClockDivider = int(int32(binary.LittleEndian.Uint32(buf[28:])))
+	st.ClockCounter = math.Float64frombits(binary.LittleEndian.Uint64(buf[32:]))
+	st.NoiseOut = buf[40] != 0
+	return st
 }
 
 func boolByte(b bool) uint8 {
@@ -0,0 +1,123 @@
+package sn76489
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+const extendedVersion = 1
+
+// extendedHeaderSize is the size of the extended fields before the embedded
+// Serialize state.
+const extendedHeaderSize = 23
+
+// ExtendedSerializeSize is the number of bytes needed by SerializeExtended.
+const ExtendedSerializeSize = extendedHeaderSize + SerializeSize
+
+// ErrConfigMismatch is returned by DeserializeExtended when the state was
+// saved from a chip with a different Config, clock or sample rate.
+var ErrConfigMismatch = errors.New("sn76489: state config does not match chip")
+
+// SerializeExtended writes a self-describing state: the chip's Config, clock
+// frequency, sample rate and gain, followed by the Serialize state. Returns
+// ErrBufferTooSmall if len(buf) < ExtendedSerializeSize.
+func (s *SN76489) SerializeExtended(buf []byte) error {
+	if len(buf) < ExtendedSerializeSize {
+		return ErrBufferTooSmall
+	}
+
+	copy(buf[0:4], "S76X")
+	buf[4] = extendedVersion
+	buf[5] = uint8(s.config.LFSRBits)
+	binary.LittleEndian.PutUint16(buf[6:], s.config.WhiteNoiseTaps)
+	buf[8] = uint8(s.config.ToneZero)
+	binary.LittleEndian.PutUint16(buf[9:], s.config.LFSRInit)
+	binary.LittleEndian.PutUint32(buf[11:], uint32(s.clockFreq))
+	binary.LittleEndian.PutUint32(buf[15:], uint32(s.sampleRate))
+	binary.LittleEndian.PutUint32(buf[19:], math.Float32bits(s.gain))
+	return s.Serialize(buf[extendedHeaderSize:])
+}
+
+// extendedHeader holds the decoded extended fields.
+type extendedHeader struct {
+	config     Config
+	clockFreq  int
+	sampleRate int
+	gain       float32
+}
+
+// decodeExtended checks and decodes the extended fields of buf.
+func decodeExtended(buf []byte) (extendedHeader, error) {
+	var h extendedHeader
+	if len(buf) < extendedHeaderSize+1 {
+		return h, ErrBufferTooSmall
+	}
+	if string(buf[0:4]) != "S76X" || buf[4] != extendedVersion {
+		return h, ErrUnsupportedVersion
+	}
+
+	h.config = Config{
+		LFSRBits:       int(buf[5]),
+		WhiteNoiseTaps: binary.LittleEndian.Uint16(buf[6:]),
+		ToneZero:       ToneZero(buf[8]),
+		LFSRInit:       binary.LittleEndian.Uint16(buf[9:]),
+	}
+	h.clockFreq = int(binary.LittleEndian.Uint32(buf[11:]))
+	h.sampleRate = int(binary.LittleEndian.Uint32(buf[15:]))
+	h.gain = math.Float32frombits(binary.LittleEndian.Uint32(buf[19:]))
+
+	if h.config.LFSRBits < 2 || h.config.LFSRBits > 16 {
+		return h, fmt.Errorf("%w: LFSR bits = %d", ErrInvalidState, h.config.LFSRBits)
+	}
+	if h.config.ToneZero != ToneZeroAsOne && h.config.ToneZero != ToneZeroAs1024 {
+		return h, fmt.Errorf("%w: tone zero mode = %d", ErrInvalidState, h.config.ToneZero)
+	}
+	if h.clockFreq <= 0 || h.sampleRate <= 0 {
+		return h, fmt.Errorf("%w: clock %d Hz, sample rate %d Hz", ErrInvalidState, h.clockFreq, h.sampleRate)
+	}
+	if math.IsNaN(float64(h.gain)) || math.IsInf(float64(h.gain), 0) {
+		return h, fmt.Errorf("%w: gain = %v", ErrInvalidState, h.gain)
+	}
+	return h, nil
+}
+
+// DeserializeExtended restores state written by SerializeExtended. Returns
+// an error wrapping ErrConfigMismatch if the state was saved from a chip
+// with a different Config, clock frequency or sample rate; use
+// NewFromExtended to restore onto a freshly constructed chip instead. Gain
+// is restored. The chip is left unchanged on error.
+func (s *SN76489) DeserializeExtended(buf []byte) error {
+	h, err := decodeExtended(buf)
+	if err != nil {
+		return err
+	}
+	if h.config != s.config {
+		return fmt.Errorf("%w: saved %+v, chip %+v", ErrConfigMismatch, h.config, s.config)
+	}
+	if h.clockFreq != s.clockFreq || h.sampleRate != s.sampleRate {
+		return fmt.Errorf("%w: saved %d Hz / %d Hz, chip %d Hz / %d Hz",
+			ErrConfigMismatch, h.clockFreq, h.sampleRate, s.clockFreq, s.sampleRate)
+	}
+	if err := s.Deserialize(buf[extendedHeaderSize:]); err != nil {
+		return err
+	}
+	s.gain = h.gain
+	return nil
+}
+
+// NewFromExtended constructs a chip from state written by SerializeExtended,
+// using the saved Config, clock frequency, sample rate and gain. bufferSize
+// is host config and is not saved; pass the same value as to New.
+func NewFromExtended(buf []byte, bufferSize int) (*SN76489, error) {
+	h, err := decodeExtended(buf)
+	if err != nil {
+		return nil, err
+	}
+	s := New(h.clockFreq, h.sampleRate, bufferSize, h.config)
+	if err := s.DeserializeExtended(buf); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
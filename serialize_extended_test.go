@@ -0,0 +1,139 @@
+package sn76489
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestSN76489_SerializeExtendedRoundTrip verifies the extended format
+// restores state and gain onto a matching chip.
+func TestSN76489_SerializeExtendedRoundTrip(t *testing.T) {
+	chip := New(3579545, 48000, 800, TI)
+	setupSerializeState(chip)
+	chip.SetGain(0.6)
+
+	buf := make([]byte, ExtendedSerializeSize)
+	if err := chip.SerializeExtended(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	chip2 := New(3579545, 48000, 800, TI)
+	if err := chip2.DeserializeExtended(buf); err != nil {
+		t.Fatal(err)
+	}
+	if chip2.SaveState() != chip.SaveState() {
+		t.Errorf("State mismatch:\noriginal=%+v\nloaded=%+v", chip.SaveState(), chip2.SaveState())
+	}
+	if chip2.GetGain() != 0.6 {
+		t.Errorf("Gain: expected 0.6, got %f", chip2.GetGain())
+	}
+}
+
+// TestSN76489_DeserializeExtendedMismatch verifies a state from a chip with
+// a different variant, clock or sample rate is rejected without changes.
+func TestSN76489_DeserializeExtendedMismatch(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	setupSerializeState(chip)
+	buf := make([]byte, ExtendedSerializeSize)
+	if err := chip.SerializeExtended(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	custom := Sega
+	custom.LFSRInit = 0x4000
+	targets := []*SN76489{
+		New(3579545, 48000, 800, TI),
+		New(3579545, 48000, 800, custom),
+		New(3546893, 48000, 800, Sega),
+		New(3579545, 44100, 800, Sega),
+	}
+	for i, target := range targets {
+		before := target.SaveState()
+		if err := target.DeserializeExtended(buf); !errors.Is(err, ErrConfigMismatch) {
+			t.Errorf("Target %d: expected ErrConfigMismatch, got %v", i, err)
+		}
+		if target.SaveState() != before || target.GetGain() != 0.25 {
+			t.Errorf("Target %d: chip changed on rejected DeserializeExtended", i)
+		}
+	}
+}
+
+// TestNewFromExtended verifies a fresh chip is constructed with the saved
+// Config, clock, rate and gain and continues with identical output.
+func TestNewFromExtended(t *testing.T) {
+	chip := New(3546893, 44100, 800, TI)
+	setupSerializeState(chip)
+	chip.SetGain(0.5)
+	buf := make([]byte, ExtendedSerializeSize)
+	if err := chip.SerializeExtended(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	chip2, err := NewFromExtended(buf, 800)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chip2.GetConfig() != TI || chip2.GetClockFreq() != 3546893 || chip2.GetSampleRate() != 44100 {
+		t.Errorf("Construction params: config=%+v clock=%d rate=%d",
+			chip2.GetConfig(), chip2.GetClockFreq(), chip2.GetSampleRate())
+	}
+	if chip2.GetGain() != 0.5 {
+		t.Errorf("Gain: expected 0.5, got %f", chip2.GetGain())
+	}
+
+	chip.GenerateSamples(10000)
+	origBuf, origCount := chip.GetBuffer()
+	chip2.GenerateSamples(10000)
+	loadBuf, loadCount := chip2.GetBuffer()
+	if origCount != loadCount {
+		t.Fatalf("Sample count mismatch: original=%d, loaded=%d", origCount, loadCount)
+	}
+	for i := 0; i < origCount; i++ {
+		if origBuf[i] != loadBuf[i] {
+			t.Fatalf("Sample %d differs: original=%f, loaded=%f", i, origBuf[i], loadBuf[i])
+		}
+	}
+}
+
+// TestSN76489_ExtendedErrors verifies buffer size, magic and field checks.
+func TestSN76489_ExtendedErrors(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	if err := chip.SerializeExtended(make([]byte, ExtendedSerializeSize-1)); !errors.Is(err, ErrBufferTooSmall) {
+		t.Errorf("Short serialize buffer: expected ErrBufferTooSmall, got %v", err)
+	}
+
+	buf := make([]byte, ExtendedSerializeSize)
+	if err := chip.SerializeExtended(buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFromExtended(buf[:10], 800); !errors.Is(err, ErrBufferTooSmall) {
+		t.Errorf("Short buffer: expected ErrBufferTooSmall, got %v", err)
+	}
+	if err := chip.DeserializeExtended(buf[:ExtendedSerializeSize-1]); !errors.Is(err, ErrBufferTooSmall) {
+		t.Errorf("Truncated state: expected ErrBufferTooSmall, got %v", err)
+	}
+
+	bad := append([]byte{}, buf...)
+	bad[0] = 'X'
+	if _, err := NewFromExtended(bad, 800); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Bad magic: expected ErrUnsupportedVersion, got %v", err)
+	}
+
+	bad = append([]byte{}, buf...)
+	bad[5] = 40 // LFSR bits
+	if _, err := NewFromExtended(bad, 800); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Bad LFSR width: expected ErrInvalidState, got %v", err)
+	}
+
+	bad = append([]byte{}, buf...)
+	bad[11], bad[12], bad[13], bad[14] = 0, 0, 0, 0 // Clock
+	if _, err := NewFromExtended(bad, 800); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Zero clock: expected ErrInvalidState, got %v", err)
+	}
+
+	bad = append([]byte{}, buf...)
+	bad[extendedHeaderSize+24] = 99 // Embedded volume
+	if err := chip.DeserializeExtended(bad); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Bad embedded state: expected ErrInvalidState, got %v", err)
+	}
+}
@@ -62,16 +62,26 @@ type SN76489 struct {
 	latchedChannel uint8 // Which channel is latched (0-3)
 	latchedType    uint8 // 0 = tone/noise, 1 = volume
 
+	// Construction parameters
+	config     Config
+	clockFreq  int
+	sampleRate int
+
 	// Variant-derived config
 	feedbackShift  uint   // LFSRBits - 1 (14 for TI, 15 for Sega)
 	lfsrInitial    uint16 // 1 << feedbackShift (0x4000 or 0x8000)
 	whiteNoiseTaps uint16 // Copy from config
 	toneZeroValue  uint16 // 1 for Sega, 1024 for TI
 
+	// Power-on randomization (see SetPowerOnRandomization)
+	randomPowerOn bool
+	powerOnSeed   uint64
+
 	// Clock info
 	clocksPerSample float64
 	clockCounter    float64
-	clockDivider    int // Divides input clock by 16
+	clockDivider    int    // Divides input clock by 16
+	clocks          uint64 // Input clocks since New or a power-cycle reset
 
 	// Gain applied to mixed output (default 0.25 = /4.0)
 	gain float32
@@ -80,6 +90,25 @@ type SN76489 struct {
 	channelBuffers [4][]float32 // per-channel raw amplitude buffers
 	mixBuffer      []float32    // mono mix output (filled by GetBuffer)
 	bufferPos      int
+
+	// Debug hooks (not copied by Clone)
+	subscribers []eventSubscriber
+	nextSubID   int
+	writeTrace  func(WriteTrace)
+
+	breakpoints  []breakpoint
+	nextBreakID  int
+	breakHandler func(BreakpointHit)
+	pendingBreak BreakpointHit
+	breakPending bool
+	breakDepth   int  // Nesting of breakpoint checks (handlers may Write)
+	breakRemoved bool // A breakpoint was removed during a check
+
+	timelineOn bool
+	timeline   []TimelineEntry
+
+	scopeOn       bool
+	scopeTriggers [4][]int // Trigger sample indices per channel (see SetScopeRecording)
 }
 
 // New creates a new SN76489 instance
@@ -99,6 +128,9 @@ func New(clockFreq int, sampleRate int, bufferSize int, config Config) *SN76489
 	}
 
 	p := &SN76489{
+		config:          config,
+		clockFreq:       clockFreq,
+		sampleRate:      sampleRate,
 		clocksPerSample: float64(clockFreq) / float64(sampleRate),
 		gain:            0.25,
 		mixBuffer:       make([]float32, bufferSize),
@@ -121,29 +153,83 @@ func New(clockFreq int, sampleRate int, bufferSize int, config Config) *SN76489
 	return p
 }
 
-// Reset resets all chip state to power-on defaults.
-// Gain is not reset since it is host-side audio config, not chip state.
+// ResetKind selects what a reset reinitializes. See ResetWith.
+type ResetKind int
+
+const (
+	// ResetPowerCycle reinitializes everything, as when power is applied:
+	// registers, volumes (silent), latch, counters, output flip-flops, LFSR,
+	// clock divider, sample phase and buffer position. Power-on
+	// randomization applies if enabled.
+	ResetPowerCycle ResetKind = iota
+
+	// ResetChipLine models a reset signal reaching the chip: counters,
+	// output flip-flops, noise toggle and output, LFSR and clock divider are
+	// reinitialized, while tone and noise registers, volumes and the latch
+	// keep their values. Host output state (sample phase and buffer
+	// position) is untouched, so it can be applied mid-frame.
+	ResetChipLine
+
+	// ResetBufferOnly resets only the buffer position, for systems where the
+	// reset line does not reach the PSG. Equivalent to ResetBuffer.
+	ResetBufferOnly
+)
+
+// Reset resets all chip state to power-on defaults, or to a seeded random
+// power-on state if enabled with SetPowerOnRandomization. Equivalent to
+// ResetWith(ResetPowerCycle).
 func (s *SN76489) Reset() {
-	s.toneReg = [3]uint16{}
+	s.ResetWith(ResetPowerCycle)
+}
+
+// ResetWith performs a reset of the given kind.
+func (s *SN76489) ResetWith(kind ResetKind) {
+	switch kind {
+	case ResetPowerCycle:
+		s.toneReg = [3]uint16{}
+		s.noiseReg = 0
+		for i := range s.volume {
+			s.volume[i] = 0x0F
+		}
+		s.latchedChannel = 0
+		s.latchedType = 0
+		s.resetSequencer()
+		s.clockCounter = 0
+		s.clocks = 0
+		if s.randomPowerOn {
+			s.randomizePowerOn()
+		}
+		s.ResetBuffer()
+	case ResetChipLine:
+		s.resetSequencer()
+	case ResetBufferOnly:
+		s.ResetBuffer()
+	}
+}
+
+// resetSequencer reinitializes the counters, output flip-flops, LFSR and
+// clock divider.
+func (s *SN76489) resetSequencer() {
 	s.toneCounter = [3]uint16{}
 	s.toneOutput = [3]bool{}
-	s.noiseReg = 0
 	s.noiseCounter = 0
 	s.noiseShift = s.lfsrInitial
 	s.noiseToggle = false
 	s.noiseOut = false
-	for i := range s.volume {
-		s.volume[i] = 0x0F
-	}
-	s.latchedChannel = 0
-	s.latchedType = 0
 	s.clockDivider = 0
-	s.clockCounter = 0
-	s.bufferPos = 0
 }
 
 // Write handles writes to the SN76489
 func (s *SN76489) Write(value uint8) {
+	if len(s.subscribers) == 0 && s.writeTrace == nil && len(s.breakpoints) == 0 && !s.timelineOn {
+		s.write(value)
+		return
+	}
+	s.writeHooked(value)
+}
+
+// write applies a byte written to the chip.
+func (s *SN76489) write(value uint8) {
 	if value&0x80 != 0 {
 		// LATCH/DATA byte: 1 CC T DDDD
 		// CC = channel (0-2 tone, 3 noise)
@@ -188,6 +274,8 @@ func (s *SN76489) Write(value uint8) {
 
 // Clock advances the SN76489 by one clock cycle (internal, doesn't generate samples)
 func (s *SN76489) Clock() {
+	s.clocks++
+
 	// SN76489 divides input clock by 16
 	s.clockDivider++
 	if s.clockDivider < 16 {
@@ -212,6 +300,9 @@ func (s *SN76489) Clock() {
 			if s.toneCounter[i] == 0 {
 				s.toneCounter[i] = regVal
 				s.toneOutput[i] = !s.toneOutput[i]
+				if s.scopeOn && s.toneOutput[i] {
+					s.scopeTrigger(i)
+				}
 			}
 		}
 	}
@@ -246,6 +337,7 @@ func (s *SN76489) Clock() {
 		// matching real hardware where the LFSR clocks at half
 		// the counter rate.
 		if s.noiseToggle {
+			prevOut := s.noiseOut
 			s.noiseOut = (s.noiseShift & 1) != 0
 
 			// Calculate feedback bit
@@ -264,8 +356,15 @@ func (s *SN76489) Clock() {
 			}
 
 			s.noiseShift = (s.noiseShift >> 1) | feedback
+			if s.scopeOn {
+				s.scopeNoiseTrigger(prevOut)
+			}
 		}
 	}
+
+	if len(s.breakpoints) > 0 {
+		s.checkStateBreakpoints()
+	}
 }
 
 // Sample generates one audio sample using unipolar output matching
@@ -293,6 +392,15 @@ func (s *SN76489) Sample() float32 {
 // Called once at the start of each frame when using Run for cycle-accurate emulation.
 func (s *SN76489) ResetBuffer() {
 	s.bufferPos = 0
+	if s.timelineOn {
+		s.timeline = s.timeline[:0]
+		s.recordTimeline()
+	}
+	if s.scopeOn {
+		for ch := range s.scopeTriggers {
+			s.scopeTriggers[ch] = s.scopeTriggers[ch][:0]
+		}
+	}
 }
 
 // Run advances the chip by the given number of clocks, accumulating samples
@@ -377,6 +485,31 @@ func (s *SN76489) ClocksPerSample() float64 {
 	return s.clocksPerSample
 }
 
+// GetConfig returns the chip variant config passed to New.
+func (s *SN76489) GetConfig() Config {
+	return s.config
+}
+
+// GetClockFreq returns the chip clock frequency passed to New.
+func (s *SN76489) GetClockFreq() int {
+	return s.clockFreq
+}
+
+// GetSampleRate returns the audio output sample rate passed to New.
+func (s *SN76489) GetSampleRate() int {
+	return s.sampleRate
+}
+
+// GetClock returns the number of input clocks run since New or the last
+// power-cycle reset. It is a timestamp for events, traces and breakpoints.
+// CopyStateFrom and Rewind roll it back with the state, so after a rollback
+// timestamps match a run without one. It is not part of State or the
+// Serialize format: LoadState and Deserialize leave it alone, and a host
+// restoring its own snapshots should follow with SetClock.
+func (s *SN76489) GetClock() uint64 {
+	return s.clocks
+}
+
 // GetToneReg returns the 10-bit tone register for the given channel (0-2)
 func (s *SN76489) GetToneReg(ch int) uint16 {
 	return s.toneReg[ch]
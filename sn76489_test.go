@@ -1,7 +1,13 @@
 package sn76489
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
 	"math"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -765,6 +771,355 @@ func TestSN76489_SerializeShortBuffer(t *testing.T) {
 	}
 }
 
+// TestSN76489_DeserializeSentinelErrors verifies the typed errors returned
+// for short, wrong-version and out-of-range buffers.
+func TestSN76489_DeserializeSentinelErrors(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	buf := make([]byte, SerializeSize)
+	if err := chip.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := chip.Deserialize(buf[:10]); !errors.Is(err, ErrBufferTooSmall) {
+		t.Errorf("Short buffer: expected ErrBufferTooSmall, got %v", err)
+	}
+	if err := chip.Serialize(make([]byte, 10)); !errors.Is(err, ErrBufferTooSmall) {
+		t.Errorf("Short serialize buffer: expected ErrBufferTooSmall, got %v", err)
+	}
+
+	bad := append([]byte{}, buf...)
+	bad[0] = 99
+	if err := chip.Deserialize(bad); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Bad version: expected ErrUnsupportedVersion, got %v", err)
+	}
+}
+
+// TestSN76489_DeserializeRejectsOutOfRange verifies each out-of-range field
+// is rejected and leaves the chip unchanged.
+func TestSN76489_DeserializeRejectsOutOfRange(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		patch func(buf []byte)
+	}{
+		{"tone register", func(buf []byte) { binary.LittleEndian.PutUint16(buf[1:], 0x400) }},
+		{"tone counter", func(buf []byte) { binary.LittleEndian.PutUint16(buf[9:], 0x401) }},
+		{"tone output flag", func(buf []byte) { buf[14] = 2 }},
+		{"noise register", func(buf []byte) { buf[16] = 0x08 }},
+		{"noise counter", func(buf []byte) { binary.LittleEndian.PutUint16(buf[17:], 0xFFFF) }},
+		{"noise toggle flag", func(buf []byte) { buf[21] = 0xFF }},
+		{"volume", func(buf []byte) { buf[24] = 200 }},
+		{"latched channel", func(buf []byte) { buf[26] = 7 }},
+		{"latched type", func(buf []byte) { buf[27] = 2 }},
+		{"clock divider", func(buf []byte) { binary.LittleEndian.PutUint32(buf[28:], 16) }},
+		{"negative clock divider", func(buf []byte) { binary.LittleEndian.PutUint32(buf[28:], 0xFFFFFFFF) }},
+		{"NaN clock counter", func(buf []byte) { binary.LittleEndian.PutUint64(buf[32:], math.Float64bits(math.NaN())) }},
+		{"negative clock counter", func(buf []byte) { binary.LittleEndian.PutUint64(buf[32:], math.Float64bits(-1)) }},
+		{"noise output flag", func(buf []byte) { buf[40] = 3 }},
+	}
+
+	chip := New(3579545, 48000, 800, Sega)
+	setupSerializeState(chip)
+	good := make([]byte, SerializeSize)
+	if err := chip.Serialize(good); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range testCases {
+		target := New(3579545, 48000, 800, Sega)
+		before := target.SaveState()
+
+		buf := append([]byte{}, good...)
+		tc.patch(buf)
+		if err := target.Deserialize(buf); !errors.Is(err, ErrInvalidState) {
+			t.Errorf("%s: expected ErrInvalidState, got %v", tc.desc, err)
+		}
+		if target.SaveState() != before {
+			t.Errorf("%s: chip state changed on rejected Deserialize", tc.desc)
+		}
+	}
+}
+
+// TestSN76489_DeserializeLFSRWidth verifies an LFSR value wider than the
+// chip's configured width is rejected.
+func TestSN76489_DeserializeLFSRWidth(t *testing.T) {
+	chip := New(3579545, 48000, 800, TI)
+	buf := make([]byte, SerializeSize)
+	if err := chip.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	binary.LittleEndian.PutUint16(buf[19:], 0x7FFF)
+	if err := chip.Deserialize(buf); err != nil {
+		t.Errorf("15-bit LFSR value should be accepted, got %v", err)
+	}
+	binary.LittleEndian.PutUint16(buf[19:], 0x8000)
+	if err := chip.Deserialize(buf); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("16-bit LFSR value on TI: expected ErrInvalidState, got %v", err)
+	}
+}
+
+// FuzzDeserialize verifies no buffer can make Deserialize, or any chip
+// operation after a successful Deserialize, panic.
+func FuzzDeserialize(f *testing.F) {
+	chip := New(3579545, 48000, 800, Sega)
+	buf := make([]byte, SerializeSize)
+	chip.Serialize(buf)
+	f.Add(append([]byte{}, buf...))
+	setupSerializeState(chip)
+	chip.Serialize(buf)
+	f.Add(append([]byte{}, buf...))
+	f.Add([]byte{serializeVersion})
+	v1 := append([]byte{}, buf[:40]...)
+	v1[0] = 1
+	f.Add(v1)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, config := range []Config{Sega, TI} {
+			chip := New(3579545, 48000, 64, config)
+			if err := chip.Deserialize(data); err != nil {
+				continue
+			}
+			chip.Sample()
+			chip.GenerateSamples(2000)
+			chip.GetBuffer()
+			for v := 0; v < 256; v++ {
+				chip.Write(uint8(v))
+				chip.Run(17)
+			}
+			chip.Sample()
+			chip.GetBuffer()
+			out := make([]byte, SerializeSize)
+			if err := chip.Serialize(out); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+}
+
+// TestSN76489_DeserializeCorpus loads every stored state blob in
+// testdata/serialize. Version 1 blobs must load with the same state as their
+// version 2 counterpart, except noiseOut which is derived from the LFSR.
+func TestSN76489_DeserializeCorpus(t *testing.T) {
+	paths, err := filepath.Glob("testdata/serialize/v*.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("No serialize corpus files found")
+	}
+
+	for _, path := range paths {
+		name := filepath.Base(path)
+		config := Sega
+		if strings.Contains(name, "_ti") {
+			config = TI
+		}
+		buf, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		chip := New(3579545, 48000, 800, config)
+		if err := chip.Deserialize(buf); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		// Re-serializing always produces the current version.
+		out := make([]byte, SerializeSize)
+		if err := chip.Serialize(out); err != nil {
+			t.Fatal(err)
+		}
+		if out[0] != serializeVersion {
+			t.Errorf("%s: re-serialized version %d, expected %d", name, out[0], serializeVersion)
+		}
+
+		if !strings.HasPrefix(name, "v1_") {
+			if !bytes.Equal(out, buf) {
+				t.Errorf("%s: current-version blob did not round-trip", name)
+			}
+			continue
+		}
+
+		current, err := os.ReadFile(filepath.Join("testdata/serialize", "v2_"+strings.TrimPrefix(name, "v1_")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ref := New(3579545, 48000, 800, config)
+		if err := ref.Deserialize(current); err != nil {
+			t.Fatal(err)
+		}
+		expected := ref.SaveState()
+		expected.NoiseOut = expected.NoiseShift&1 != 0
+		if got := chip.SaveState(); got != expected {
+			t.Errorf("%s: migrated state mismatch:\nexpected=%+v\ngot=     %+v", name, expected, got)
+		}
+	}
+}
+
+// TestSN76489_DeserializeV1Fields verifies a stored version 1 blob decodes
+// to the expected register values.
+func TestSN76489_DeserializeV1Fields(t *testing.T) {
+	buf, err := os.ReadFile("testdata/serialize/v1_active_sega.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buf) != 40 || buf[0] != 1 {
+		t.Fatalf("Unexpected v1 blob: %d bytes, version %d", len(buf), buf[0])
+	}
+
+	chip := New(3579545, 48000, 800, Sega)
+	if err := chip.Deserialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	for ch, expected := range []uint16{0x1AB, 0x3F5, 0x0A2} {
+		if got := chip.GetToneReg(ch); got != expected {
+			t.Errorf("ToneReg[%d]: expected 0x%03X, got 0x%03X", ch, expected, got)
+		}
+	}
+	for ch, expected := range []uint8{0x0, 0x3, 0x7, 0xB} {
+		if got := chip.GetVolume(ch); got != expected {
+			t.Errorf("Volume[%d]: expected 0x%X, got 0x%X", ch, expected, got)
+		}
+	}
+	if got := chip.GetNoiseReg(); got != 0x05 {
+		t.Errorf("NoiseReg: expected 0x05, got 0x%02X", got)
+	}
+	if got := chip.GetNoiseOutput(); got != (chip.GetNoiseShift()&1 != 0) {
+		t.Errorf("NoiseOut should be derived from the LFSR low bit, got %v", got)
+	}
+
+	// A version 1 buffer one byte short is rejected as too small.
+	if err := chip.Deserialize(buf[:39]); !errors.Is(err, ErrBufferTooSmall) {
+		t.Errorf("Short v1 buffer: expected ErrBufferTooSmall, got %v", err)
+	}
+}
+
+// setupSerializeState puts chip into a non-trivial state for save state tests.
+func setupSerializeState(chip *SN76489) {
+	chip.Write(0x8B) // channel 0 tone low nibble = 0xB
+	chip.Write(0x1A) // tone = 0x1AB
+	chip.Write(0x90) // channel 0 volume = 0 (max)
+	chip.Write(0xA5) // channel 1 tone low nibble = 5
+	chip.Write(0x3F) // tone = 0x3F5
+	chip.Write(0xB3) // channel 1 volume = 3
+	chip.Write(0xC2) // channel 2 tone low nibble = 2
+	chip.Write(0x0A) // tone = 0x0A2
+	chip.Write(0xD7) // channel 2 volume = 7
+	chip.Write(0xE5) // noise = white, rate 1
+	chip.Write(0xFB) // noise volume = 0xB
+	chip.GenerateSamples(5003)
+}
+
+// TestSN76489_SaveStateMatchesSerialize verifies every State field holds the
+// same value the byte format stores at its offset.
+func TestSN76489_SaveStateMatchesSerialize(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	setupSerializeState(chip)
+
+	st := chip.SaveState()
+	buf := make([]byte, SerializeSize)
+	if err := chip.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if got := binary.LittleEndian.Uint16(buf[1+i*2:]); got != st.ToneReg[i] {
+			t.Errorf("ToneReg[%d]: state=%d, bytes=%d", i, st.ToneReg[i], got)
+		}
+		if got := binary.LittleEndian.Uint16(buf[7+i*2:]); got != st.ToneCounter[i] {
+			t.Errorf("ToneCounter[%d]: state=%d, bytes=%d", i, st.ToneCounter[i], got)
+		}
+		if got := buf[13+i] != 0; got != st.ToneOutput[i] {
+			t.Errorf("ToneOutput[%d]: state=%v, bytes=%v", i, st.ToneOutput[i], got)
+		}
+	}
+	if buf[16] != st.NoiseReg {
+		t.Errorf("NoiseReg: state=%d, bytes=%d", st.NoiseReg, buf[16])
+	}
+	if got := binary.LittleEndian.Uint16(buf[17:]); got != st.NoiseCounter {
+		t.Errorf("NoiseCounter: state=%d, bytes=%d", st.NoiseCounter, got)
+	}
+	if got := binary.LittleEndian.Uint16(buf[19:]); got != st.NoiseShift {
+		t.Errorf("NoiseShift: state=0x%04X, bytes=0x%04X", st.NoiseShift, got)
+	}
+	if got := buf[21] != 0; got != st.NoiseToggle {
+		t.Errorf("NoiseToggle: state=%v, bytes=%v", st.NoiseToggle, got)
+	}
+	for i := 0; i < 4; i++ {
+		if buf[22+i] != st.Volume[i] {
+			t.Errorf("Volume[%d]: state=%d, bytes=%d", i, st.Volume[i], buf[22+i])
+		}
+	}
+	if buf[26] != st.LatchedChannel || buf[27] != st.LatchedType {
+		t.Errorf("Latch: state=(%d,%d), bytes=(%d,%d)", st.LatchedChannel, st.LatchedType, buf[26], buf[27])
+	}
+	if got := int(int32(binary.LittleEndian.Uint32(buf[28:]))); got != st.ClockDivider {
+		t.Errorf("ClockDivider: state=%d, bytes=%d", st.ClockDivider, got)
+	}
+	if got := math.Float64frombits(binary.LittleEndian.Uint64(buf[32:])); got != st.ClockCounter {
+		t.Errorf("ClockCounter: state=%f, bytes=%f", st.ClockCounter, got)
+	}
+	if got := buf[40] != 0; got != st.NoiseOut {
+		t.Errorf("NoiseOut: state=%v, bytes=%v", st.NoiseOut, got)
+	}
+}
+
+// TestSN76489_LoadStateMatchesDeserialize verifies LoadState and Deserialize
+// produce identical chips, byte for byte.
+func TestSN76489_LoadStateMatchesDeserialize(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	setupSerializeState(chip)
+
+	buf := make([]byte, SerializeSize)
+	if err := chip.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	viaState := New(3579545, 48000, 800, Sega)
+	viaState.LoadState(chip.SaveState())
+	viaBytes := New(3579545, 48000, 800, Sega)
+	if err := viaBytes.Deserialize(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if viaState.SaveState() != viaBytes.SaveState() {
+		t.Errorf("LoadState and Deserialize differ:\nstate=%+v\nbytes=%+v", viaState.SaveState(), viaBytes.SaveState())
+	}
+	buf2 := make([]byte, SerializeSize)
+	if err := viaState.Serialize(buf2); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, buf2) {
+		t.Errorf("Serialize after LoadState differs:\noriginal=% X\nloaded=  % X", buf, buf2)
+	}
+}
+
+// TestSN76489_SaveLoadStateContinuity verifies a chip restored with LoadState
+// produces identical output to the original from that point forward.
+func TestSN76489_SaveLoadStateContinuity(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	setupSerializeState(chip)
+	state := chip.SaveState()
+
+	chip.GenerateSamples(10000)
+	origBuf, origCount := chip.GetBuffer()
+
+	chip2 := New(3579545, 48000, 800, Sega)
+	chip2.LoadState(state)
+	chip2.GenerateSamples(10000)
+	loadBuf, loadCount := chip2.GetBuffer()
+
+	if origCount != loadCount {
+		t.Fatalf("Sample count mismatch: original=%d, loaded=%d", origCount, loadCount)
+	}
+	for i := 0; i < origCount; i++ {
+		if origBuf[i] != loadBuf[i] {
+			t.Fatalf("Sample %d differs: original=%f, loaded=%f", i, origBuf[i], loadBuf[i])
+		}
+	}
+}
+
 // TestSN76489_DefaultGain verifies default gain (0.25) matches /4.0 behavior
 func TestSN76489_DefaultGain(t *testing.T) {
 	chip := New(3579545, 48000, 800, Sega)
@@ -1000,6 +1355,65 @@ func TestSN76489_Reset(t *testing.T) {
 	}
 }
 
+// TestSN76489_ResetWithKinds verifies each reset kind touches exactly the
+// state it documents.
+func TestSN76489_ResetWithKinds(t *testing.T) {
+	setup := func() *SN76489 {
+		chip := New(3579545, 48000, 800, Sega)
+		chip.Write(0x8F) // Channel 0 tone low nibble
+		chip.Write(0x3F) // High bits
+		chip.Write(0x90) // Channel 0 volume = 0
+		chip.Write(0xE7) // White noise, tone2 rate
+		chip.Write(0xD5) // Channel 2 volume = 5, latches channel 2 volume
+		chip.GenerateSamples(1000)
+		chip.Run(7)
+		return chip
+	}
+
+	// Power cycle: identical to Reset.
+	chip := setup()
+	chip.ResetWith(ResetPowerCycle)
+	if chip.SaveState() != New(3579545, 48000, 800, Sega).SaveState() {
+		t.Errorf("ResetPowerCycle: expected power-on state, got %+v", chip.SaveState())
+	}
+	if _, n := chip.GetBuffer(); n != 0 {
+		t.Errorf("ResetPowerCycle: buffer position expected 0, got %d", n)
+	}
+
+	// Chip reset line: registers, volumes and latch kept; sequencer reset;
+	// host output state kept.
+	chip = setup()
+	before := chip.SaveState()
+	_, samples := chip.GetBuffer()
+	chip.ResetWith(ResetChipLine)
+	got := chip.SaveState()
+	want := before
+	want.ToneCounter = [3]uint16{}
+	want.ToneOutput = [3]bool{}
+	want.NoiseCounter = 0
+	want.NoiseShift = 0x8000
+	want.NoiseToggle = false
+	want.NoiseOut = false
+	want.ClockDivider = 0
+	if got != want {
+		t.Errorf("ResetChipLine:\nexpected %+v\ngot      %+v", want, got)
+	}
+	if _, n := chip.GetBuffer(); n != samples {
+		t.Errorf("ResetChipLine: buffer position expected %d, got %d", samples, n)
+	}
+
+	// Buffer only: chip state untouched.
+	chip = setup()
+	before = chip.SaveState()
+	chip.ResetWith(ResetBufferOnly)
+	if chip.SaveState() != before {
+		t.Error("ResetBufferOnly: chip state changed")
+	}
+	if _, n := chip.GetBuffer(); n != 0 {
+		t.Errorf("ResetBufferOnly: buffer position expected 0, got %d", n)
+	}
+}
+
 // clockInternal advances the chip by n internal ticks (n*16 input clocks).
 func clockInternal(chip *SN76489, n int) {
 	for i := 0; i < n*16; i++ {
@@ -1264,3 +1678,104 @@ func TestSN76489_GetGainAndClocksPerSample(t *testing.T) {
 		t.Errorf("ClocksPerSample = %f, want %f", got, want)
 	}
 }
+
+// TestSN76489_CloneIdenticalOutput verifies a clone produces the same audio
+// as the original and that their buffers are independent.
+func TestSN76489_CloneIdenticalOutput(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	setupSerializeState(chip)
+	chip.SetGain(0.4)
+
+	clone := chip.Clone()
+	if clone.GetGain() != 0.4 {
+		t.Errorf("Clone gain: expected 0.4, got %f", clone.GetGain())
+	}
+	cloneBuf, cloneCount := clone.GetBuffer()
+	origBuf, origCount := chip.GetBuffer()
+	if cloneCount != origCount || cloneCount == 0 {
+		t.Fatalf("Clone buffer count: expected %d, got %d", origCount, cloneCount)
+	}
+	for i := 0; i < origCount; i++ {
+		if cloneBuf[i] != origBuf[i] {
+			t.Fatalf("Clone buffer sample %d: expected %f, got %f", i, origBuf[i], cloneBuf[i])
+		}
+	}
+	if &cloneBuf[0] == &origBuf[0] {
+		t.Fatal("Clone shares the mix buffer with the original")
+	}
+	cloneCh, _ := clone.GetChannelBuffers()
+	origCh, _ := chip.GetChannelBuffers()
+	for ch := 0; ch < 4; ch++ {
+		if &cloneCh[ch][0] == &origCh[ch][0] {
+			t.Fatalf("Clone shares channel buffer %d with the original", ch)
+		}
+	}
+
+	for frame := 0; frame < 5; frame++ {
+		chip.Write(uint8(0x80 | frame))
+		clone.Write(uint8(0x80 | frame))
+		chip.GenerateSamples(59659)
+		clone.GenerateSamples(59659)
+		origBuf, origCount = chip.GetBuffer()
+		cloneBuf, cloneCount = clone.GetBuffer()
+		if origCount != cloneCount {
+			t.Fatalf("Frame %d count: original=%d, clone=%d", frame, origCount, cloneCount)
+		}
+		for i := 0; i < origCount; i++ {
+			if origBuf[i] != cloneBuf[i] {
+				t.Fatalf("Frame %d sample %d: original=%f, clone=%f", frame, i, origBuf[i], cloneBuf[i])
+			}
+		}
+	}
+}
+
+// TestSN76489_CopyStateFromRollback verifies restoring a snapshot with
+// CopyStateFrom replays a frame identically and keeps the destination's
+// own buffers and gain.
+func TestSN76489_CopyStateFromRollback(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	setupSerializeState(chip)
+	snapshot := New(3579545, 48000, 1, Sega)
+	snapshot.CopyStateFrom(chip)
+
+	chip.GenerateSamples(59659)
+	first := append([]float32{}, chip.channelBuffers[0][:chip.bufferPos]...)
+
+	chip.SetGain(0.9)
+	chip.CopyStateFrom(snapshot)
+	if chip.GetGain() != 0.9 {
+		t.Error("CopyStateFrom should not change gain")
+	}
+	if len(chip.mixBuffer) != 800 {
+		t.Error("CopyStateFrom should not replace output buffers")
+	}
+	chip.GenerateSamples(59659)
+	if chip.bufferPos != len(first) {
+		t.Fatalf("Replay count: expected %d, got %d", len(first), chip.bufferPos)
+	}
+	for i, v := range first {
+		if chip.channelBuffers[0][i] != v {
+			t.Fatalf("Replay sample %d: expected %f, got %f", i, v, chip.channelBuffers[0][i])
+		}
+	}
+	if chip.SaveState() == snapshot.SaveState() {
+		t.Error("Snapshot should not advance with the chip")
+	}
+}
+
+// TestSN76489_CopyStateFromNoAlloc verifies CopyStateFrom does not allocate.
+func TestSN76489_CopyStateFromNoAlloc(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	setupSerializeState(chip)
+	dst := New(3579545, 48000, 800, Sega)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		dst.CopyStateFrom(chip)
+	})
+	if allocs != 0 {
+		t.Errorf("CopyStateFrom allocated %.1f times per call", allocs)
+	}
+	if dst.SaveState() != chip.SaveState() {
+		t.Error("CopyStateFrom did not copy the state")
+	}
+}
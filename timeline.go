@@ -0,0 +1,78 @@
+package sn76489
+
+// Registers holds the register values set by writes.
+type Registers struct {
+	ToneReg  [3]uint16 // 10-bit tone registers
+	Volume   [4]uint8  // 4-bit volumes (0-2 = tone channels, 3 = noise)
+	NoiseReg uint8     // 3-bit noise control register
+}
+
+// GetRegisters returns the current register values.
+func (s *SN76489) GetRegisters() Registers {
+	return Registers{ToneReg: s.toneReg, Volume: s.volume, NoiseReg: s.noiseReg}
+}
+
+// TimelineEntry is the register state in effect from an output sample
+// onward, until the next entry.
+type TimelineEntry struct {
+	Sample    int    // Index into the current frame's sample buffers
+	Clock     uint64 // Input clock position of the write (see GetClock)
+	Registers Registers
+}
+
+// SetTimelineRecording enables or disables the register timeline. While
+// enabled, every Write that changes a register appends an entry at the
+// current sample index, and each ResetBuffer (including GenerateSamples)
+// starts a new frame with an entry at sample 0. Writes before the same
+// sample collapse into one entry, so a latch/data pair records its final
+// value. Enabling mid-frame starts the timeline at the current sample.
+// LoadState and power-cycle resets also start a new frame; the debug
+// setters bypass Write and are not recorded.
+func (s *SN76489) SetTimelineRecording(enabled bool) {
+	s.timelineOn = enabled
+	s.timeline = s.timeline[:0]
+	if enabled {
+		s.recordTimeline()
+	}
+}
+
+// GetTimelineRecording reports whether the register timeline is enabled.
+func (s *SN76489) GetTimelineRecording() bool {
+	return s.timelineOn
+}
+
+// Timeline returns the current frame's register timeline, ordered by sample
+// index. The slice is reused and is only valid until the next ResetBuffer.
+//
+//	chip.SetTimelineRecording(true)
+//	chip.ResetBuffer()
+//	// ... Write and Run for one frame ...
+//	for _, e := range chip.Timeline() {
+//		ui.Mark(e.Sample, e.Registers)
+//	}
+func (s *SN76489) Timeline() []TimelineEntry {
+	return s.timeline
+}
+
+// RegistersAt returns the registers in effect for the given sample index of
+// the current frame. Without a recorded entry at or before sample it returns
+// the current registers.
+func (s *SN76489) RegistersAt(sample int) Registers {
+	for i := len(s.timeline) - 1; i >= 0; i-- {
+		if s.timeline[i].Sample <= sample {
+			return s.timeline[i].Registers
+		}
+	}
+	return s.GetRegisters()
+}
+
+// recordTimeline records the current registers at the current sample index,
+// replacing an entry already at that index.
+func (s *SN76489) recordTimeline() {
+	e := TimelineEntry{Sample: s.bufferPos, Clock: s.clocks, Registers: s.GetRegisters()}
+	if n := len(s.timeline); n > 0 && s.timeline[n-1].Sample == e.Sample {
+		s.timeline[n-1] = e
+		return
+	}
+	s.timeline = append(s.timeline, e)
+}
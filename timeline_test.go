@@ -0,0 +1,91 @@
+package sn76489
+
+import "testing"
+
+// TestSN76489_Timeline verifies entries are recorded at the sample index of
+// each changing write and RegistersAt resolves samples between them.
+func TestSN76489_Timeline(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	chip.SetTimelineRecording(true)
+	chip.ResetBuffer()
+
+	chip.Run(1000)
+	_, first := chip.GetChannelBuffers()
+	writeTone(chip, 0, 0x123) // Latch and data collapse into one entry
+	chip.Run(1000)
+	_, second := chip.GetChannelBuffers()
+	chip.Write(0x95)
+	chip.Write(0x95) // Unchanged: no entry
+	chip.Run(1000)
+
+	tl := chip.Timeline()
+	if len(tl) != 3 {
+		t.Fatalf("Expected 3 entries, got %d: %+v", len(tl), tl)
+	}
+	if tl[0].Sample != 0 || tl[0].Registers.Volume[0] != 0x0F {
+		t.Errorf("Frame start entry: %+v", tl[0])
+	}
+	if tl[1].Sample != first || tl[1].Clock != 1000 || tl[1].Registers.ToneReg[0] != 0x123 {
+		t.Errorf("Tone entry: expected sample %d clock 1000, got %+v", first, tl[1])
+	}
+	if tl[2].Sample != second || tl[2].Registers.Volume[0] != 5 {
+		t.Errorf("Volume entry: expected sample %d, got %+v", second, tl[2])
+	}
+
+	if r := chip.RegistersAt(first - 1); r.ToneReg[0] != 0 {
+		t.Errorf("RegistersAt before write: expected tone 0, got 0x%X", r.ToneReg[0])
+	}
+	if r := chip.RegistersAt(second - 1); r.ToneReg[0] != 0x123 || r.Volume[0] != 0x0F {
+		t.Errorf("RegistersAt between writes: %+v", r)
+	}
+	if r := chip.RegistersAt(second); r != chip.GetRegisters() {
+		t.Errorf("RegistersAt last write: expected current registers, got %+v", r)
+	}
+}
+
+// TestSN76489_TimelineFrames verifies each frame starts a new timeline from
+// the registers at its start.
+func TestSN76489_TimelineFrames(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	chip.SetTimelineRecording(true)
+	chip.Write(0xB3)
+	chip.GenerateSamples(5000)
+
+	tl := chip.Timeline()
+	if len(tl) != 1 || tl[0].Sample != 0 || tl[0].Registers.Volume[1] != 3 {
+		t.Errorf("New frame: expected one entry with volume 3, got %+v", tl)
+	}
+
+	chip.Reset()
+	if tl := chip.Timeline(); len(tl) != 1 || tl[0].Registers.Volume[1] != 0x0F {
+		t.Errorf("After Reset: expected power-on registers, got %+v", tl)
+	}
+
+	chip.SetTimelineRecording(false)
+	chip.Write(0xB0)
+	if len(chip.Timeline()) != 0 {
+		t.Error("Timeline should be empty when disabled")
+	}
+	if r := chip.RegistersAt(0); r.Volume[1] != 0 {
+		t.Errorf("RegistersAt without a timeline: expected current registers, got %+v", r)
+	}
+}
+
+// TestSN76489_TimelineNoSideEffects verifies recording does not change
+// chip output.
+func TestSN76489_TimelineNoSideEffects(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	plain := New(3579545, 48000, 800, Sega)
+	chip.SetTimelineRecording(true)
+	for _, c := range []*SN76489{chip, plain} {
+		c.ResetBuffer()
+		writeTone(c, 1, 0x40)
+		c.Write(0xB2)
+		c.Run(3000)
+		c.Write(0xE5)
+		c.Run(3000)
+	}
+	if chip.StateHashWithOutput() != plain.StateHashWithOutput() {
+		t.Error("Timeline recording changed chip output")
+	}
+}
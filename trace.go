@@ -0,0 +1,80 @@
+package sn76489
+
+import (
+	"fmt"
+	"io"
+)
+
+// WriteTrace describes one byte passed to Write and its effect, as
+// delivered to the trace callback set by SetWriteTrace.
+type WriteTrace struct {
+	Clock     uint64 // Input clock position (see GetClock)
+	Value     uint8  // Byte written
+	Latch     bool   // Latch/data byte (bit 7 set); false for a data byte
+	Channel   int    // Channel written: latched by this byte or a previous one
+	Volume    bool   // Volume register (false = tone or noise register)
+	Register  uint16 // Resulting value of the register written
+	LFSRReset bool   // The write restarted the noise LFSR
+}
+
+// SetWriteTrace sets a callback that receives every byte passed to Write,
+// decoded, with the clock position. nil disables tracing. The callback runs
+// synchronously inside Write, after the write is applied, and must not
+// write to the chip. The trace is not copied by Clone.
+func (s *SN76489) SetWriteTrace(fn func(WriteTrace)) {
+	s.writeTrace = fn
+}
+
+// decodeWrite describes the write of value that was just applied.
+func (s *SN76489) decodeWrite(value uint8) WriteTrace {
+	t := WriteTrace{
+		Clock:   s.clocks,
+		Value:   value,
+		Latch:   value&0x80 != 0,
+		Channel: int(s.latchedChannel),
+		Volume:  s.latchedType == 1,
+	}
+	switch {
+	case t.Volume:
+		t.Register = uint16(s.volume[t.Channel])
+	case t.Channel < 3:
+		t.Register = s.toneReg[t.Channel]
+	default:
+		t.Register = uint16(s.noiseReg)
+		t.LFSRReset = true
+	}
+	return t
+}
+
+// String formats the trace as a fixed-width line suitable for diffing logs,
+// with the clock right-aligned in 10 columns, for example:
+//
+//	12345  8E latch tone0  = 0x00E
+//	12345  0F data  tone0  = 0x0FE
+//	12400  E4 latch noise  = 0x4 white rate0 LFSR reset
+func (t WriteTrace) String() string {
+	kind := "data "
+	if t.Latch {
+		kind = "latch"
+	}
+	switch {
+	case t.Volume:
+		return fmt.Sprintf("%10d  %02X %s vol%d   = %d", t.Clock, t.Value, kind, t.Channel, t.Register)
+	case t.Channel < 3:
+		return fmt.Sprintf("%10d  %02X %s tone%d  = 0x%03X", t.Clock, t.Value, kind, t.Channel, t.Register)
+	}
+	mode := "periodic"
+	if t.Register&0x04 != 0 {
+		mode = "white"
+	}
+	return fmt.Sprintf("%10d  %02X %s noise  = 0x%X %s rate%d LFSR reset",
+		t.Clock, t.Value, kind, t.Register, mode, t.Register&0x03)
+}
+
+// TraceTo returns a write trace callback that writes one String line per
+// write to w. Write errors are ignored.
+func TraceTo(w io.Writer) func(WriteTrace) {
+	return func(t WriteTrace) {
+		fmt.Fprintln(w, t.String())
+	}
+}
@@ -0,0 +1,69 @@
+package sn76489
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestSN76489_WriteTrace verifies each write is decoded with its clock,
+// byte type, channel, register and LFSR reset.
+func TestSN76489_WriteTrace(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	var traces []WriteTrace
+	chip.SetWriteTrace(func(tr WriteTrace) { traces = append(traces, tr) })
+
+	chip.Write(0x8E) // Latch tone 0, low nibble
+	chip.Run(32)
+	chip.Write(0x0F) // Data: high bits
+	chip.Write(0xB3) // Latch volume 1
+	chip.Write(0x05) // Data byte while volume 1 latched
+	chip.Write(0xE6) // Latch noise
+	chip.Write(0x03) // Data byte while noise latched
+
+	want := []WriteTrace{
+		{Clock: 0, Value: 0x8E, Latch: true, Channel: 0, Register: 0x00E},
+		{Clock: 32, Value: 0x0F, Channel: 0, Register: 0x0FE},
+		{Clock: 32, Value: 0xB3, Latch: true, Channel: 1, Volume: true, Register: 3},
+		{Clock: 32, Value: 0x05, Channel: 1, Volume: true, Register: 5},
+		{Clock: 32, Value: 0xE6, Latch: true, Channel: 3, Register: 6, LFSRReset: true},
+		{Clock: 32, Value: 0x03, Channel: 3, Register: 3, LFSRReset: true},
+	}
+	if len(traces) != len(want) {
+		t.Fatalf("Expected %d traces, got %d", len(want), len(traces))
+	}
+	for i := range want {
+		if traces[i] != want[i] {
+			t.Errorf("Trace %d: expected %+v, got %+v", i, want[i], traces[i])
+		}
+	}
+
+	chip.SetWriteTrace(nil)
+	chip.Write(0x90)
+	if len(traces) != len(want) {
+		t.Error("Trace should stop after SetWriteTrace(nil)")
+	}
+}
+
+// TestTraceTo verifies the text format.
+func TestTraceTo(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	var buf bytes.Buffer
+	chip.SetWriteTrace(TraceTo(&buf))
+
+	chip.Write(0x8E)
+	chip.Write(0x0F)
+	chip.Run(1000)
+	chip.Write(0xD2)
+	chip.Write(0xE4)
+	chip.Write(0x03)
+
+	want := "" +
+		"         0  8E latch tone0  = 0x00E\n" +
+		"         0  0F data  tone0  = 0x0FE\n" +
+		"      1000  D2 latch vol2   = 2\n" +
+		"      1000  E4 latch noise  = 0x4 white rate0 LFSR reset\n" +
+		"      1000  03 data  noise  = 0x3 periodic rate3 LFSR reset\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Trace output:\n%s\nexpected:\n%s", got, want)
+	}
+}
@@ -0,0 +1,510 @@
+package sn76489
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// VGM file layout constants (version 1.51 header).
+const (
+	vgmVersion    = 0x00000151
+	vgmHeaderSize = 0x80
+	vgmSampleRate = 44100 // VGM wait commands are in 44.1 kHz samples
+)
+
+// Header offsets of the VGM fields this package reads and writes.
+const (
+	vgmOffEOF         = 0x04
+	vgmOffVersion     = 0x08
+	vgmOffClock       = 0x0C
+	vgmOffGD3         = 0x14
+	vgmOffTotal       = 0x18
+	vgmOffLoop        = 0x1C
+	vgmOffLoopSamples = 0x20
+	vgmOffFeedback    = 0x28
+	vgmOffShiftWidth  = 0x2A
+	vgmOffFlags       = 0x2B
+	vgmOffData        = 0x34
+	vgmMinHeaderSize  = 0x40 // Header size before version 1.50
+	vgmClockMask      = 0x3FFFFFFF
+)
+
+// VGM command bytes used by the SN76489 logger.
+const (
+	vgmCmdGGStereo = 0x4F // Game Gear stereo write: 0x4F dd
+	vgmCmdPSGWrite = 0x50 // SN76489 write: 0x50 dd
+	vgmCmdWait     = 0x61 // Wait n samples: 0x61 nn nn
+	vgmCmdWait735  = 0x62 // Wait 735 samples (1/60 s)
+	vgmCmdWait882  = 0x63 // Wait 882 samples (1/50 s)
+	vgmCmdEnd      = 0x66 // End of sound data
+	vgmCmdWaitN    = 0x70 // Wait n+1 samples: 0x7n
+)
+
+// VGM SN76489 flag bits (header offset 0x2B).
+const (
+	vgmFlagFreq0Is0x400 = 0x01 // Tone register 0 behaves as 1024
+	vgmFlagNoStereo     = 0x04 // Game Gear stereo disabled (set = off)
+	vgmFlagNoClockDiv8  = 0x08 // /8 clock divider disabled (set = off)
+)
+
+// errVGMNoPSG is returned when a VGM file has no SN76489 (ClockFreq 0).
+var errVGMNoPSG = errors.New("sn76489: VGM file has no SN76489")
+
+// VGMFile is a VGM file split into its header fields, command stream and
+// GD3 tag. Only the SN76489-related header fields are decoded; the raw header
+// is kept so fields for other chips survive a round trip.
+type VGMFile struct {
+	Version      uint32 // BCD, e.g. 0x151 for 1.51
+	ClockFreq    int    // SN76489 clock in Hz, 0 if the file has no SN76489
+	TotalSamples uint32 // Length in 44.1 kHz samples
+	LoopOffset   int    // Offset into Data of the loop point, -1 if none
+	LoopSamples  uint32 // Length of the looped section in 44.1 kHz samples
+	Feedback     uint16 // SN76489 white noise taps
+	ShiftWidth   uint8  // SN76489 LFSR width in bits
+	Flags        uint8  // SN76489 flags (header offset 0x2B)
+
+	Header []byte // Raw header up to the start of Data
+	Data   []byte // Command stream, including the end marker
+	GD3    *GD3   // Metadata tag, nil if the file has none
+
+	// Compressed selects gzip (.vgz) output. ParseVGM sets it when the input
+	// was compressed, so an edited file is written back in the same form.
+	Compressed bool
+}
+
+// ReadVGM reads a VGM or VGZ file from r. See ParseVGM.
+func ReadVGM(r io.Reader) (*VGMFile, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ParseVGM(data)
+}
+
+// ParseVGM parses a VGM file. Gzip-compressed (.vgz) input is detected and
+// decompressed transparently. For uncompressed input, Data and Header alias
+// data.
+func ParseVGM(data []byte) (*VGMFile, error) {
+	compressed := isGzip(data)
+	if compressed {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if data, err = io.ReadAll(zr); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(data) < vgmMinHeaderSize || string(data[0:4]) != "Vgm " {
+		return nil, errors.New("sn76489: not a VGM file")
+	}
+
+	f := &VGMFile{
+		Version:      binary.LittleEndian.Uint32(data[vgmOffVersion:]),
+		ClockFreq:    int(binary.LittleEndian.Uint32(data[vgmOffClock:]) & vgmClockMask),
+		TotalSamples: binary.LittleEndian.Uint32(data[vgmOffTotal:]),
+		LoopOffset:   -1,
+		LoopSamples:  binary.LittleEndian.Uint32(data[vgmOffLoopSamples:]),
+		// Defaults for files older than 1.10, which predate these fields.
+		Feedback:   0x0009,
+		ShiftWidth: 16,
+	}
+	if f.Version >= 0x110 {
+		f.Feedback = binary.LittleEndian.Uint16(data[vgmOffFeedback:])
+		f.ShiftWidth = data[vgmOffShiftWidth]
+	}
+	if f.Version >= 0x151 {
+		f.Flags = data[vgmOffFlags]
+	}
+
+	end := relOffset(data, vgmOffEOF)
+	if end == 0 || end > len(data) {
+		end = len(data)
+	}
+	start := vgmMinHeaderSize
+	if f.Version >= 0x150 {
+		if off := relOffset(data, vgmOffData); off != 0 {
+			start = off
+		}
+	}
+	if start > end {
+		return nil, errors.New("sn76489: VGM data offset out of range")
+	}
+
+	dataEnd := end
+	if gd3 := relOffset(data, vgmOffGD3); gd3 != 0 {
+		if gd3 < start || gd3 > end {
+			return nil, errors.New("sn76489: VGM GD3 offset out of range")
+		}
+		f.GD3 = &GD3{}
+		if err := f.GD3.UnmarshalBinary(data[gd3:end]); err != nil {
+			return nil, err
+		}
+		dataEnd = gd3
+	}
+	if loop := relOffset(data, vgmOffLoop); loop != 0 {
+		if loop < start || loop >= dataEnd {
+			return nil, errors.New("sn76489: VGM loop offset out of range")
+		}
+		f.LoopOffset = loop - start
+	}
+
+	f.Header = data[:start]
+	f.Data = data[start:dataEnd]
+	f.Compressed = compressed
+	return f, nil
+}
+
+// isGzip reports whether data starts with the gzip magic bytes.
+func isGzip(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0x1F && data[1] == 0x8B
+}
+
+// relOffset reads a header offset field, which is relative to its own
+// position. Returns 0 if the field is 0 (unset).
+func relOffset(data []byte, pos int) int {
+	off := binary.LittleEndian.Uint32(data[pos:])
+	if off == 0 {
+		return 0
+	}
+	return pos + int(off)
+}
+
+// Config returns the chip Config described by the header's SN76489 fields.
+// Files that leave the noise fields unset (0, as many 1.10+ files do) or out
+// of range get the Sega LFSR width and taps.
+func (f *VGMFile) Config() Config {
+	config := Config{
+		LFSRBits:       int(f.ShiftWidth),
+		WhiteNoiseTaps: f.Feedback,
+		ToneZero:       ToneZeroAsOne,
+	}
+	if config.LFSRBits < 2 || config.LFSRBits > 16 || config.WhiteNoiseTaps == 0 {
+		config.LFSRBits = Sega.LFSRBits
+		config.WhiteNoiseTaps = Sega.WhiteNoiseTaps
+	}
+	if f.Flags&vgmFlagFreq0Is0x400 != 0 {
+		config.ToneZero = ToneZeroAs1024
+	}
+	return config
+}
+
+// MarshalBinary encodes the file, updating the header's size, offset and
+// SN76489 fields from f. The result is gzip-compressed if f.Compressed is set.
+func (f *VGMFile) MarshalBinary() ([]byte, error) {
+	buf, err := f.encode()
+	if err != nil || !f.Compressed {
+		return buf, err
+	}
+
+	var out bytes.Buffer
+	zw := gzip.NewWriter(&out)
+	if _, err := zw.Write(buf); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// encode returns the uncompressed file bytes.
+func (f *VGMFile) encode() ([]byte, error) {
+	var tag []byte
+	if f.GD3 != nil {
+		var err error
+		if tag, err = f.GD3.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+
+	headerSize := len(f.Header)
+	if headerSize < vgmMinHeaderSize {
+		headerSize = vgmMinHeaderSize
+	}
+	if f.Version >= 0x151 && headerSize < vgmHeaderSize {
+		headerSize = vgmHeaderSize
+	}
+	size := headerSize + len(f.Data) + len(tag)
+	buf := make([]byte, size)
+	copy(buf, f.Header)
+	copy(buf[headerSize:], f.Data)
+	copy(buf[headerSize+len(f.Data):], tag)
+
+	copy(buf[0:4], "Vgm ")
+	putRelOffset(buf, vgmOffEOF, size)
+	binary.LittleEndian.PutUint32(buf[vgmOffVersion:], f.Version)
+	clock := binary.LittleEndian.Uint32(buf[vgmOffClock:]) &^ vgmClockMask
+	binary.LittleEndian.PutUint32(buf[vgmOffClock:], clock|uint32(f.ClockFreq)&vgmClockMask)
+	binary.LittleEndian.PutUint32(buf[vgmOffTotal:], f.TotalSamples)
+	binary.LittleEndian.PutUint32(buf[vgmOffGD3:], 0)
+	if tag != nil {
+		putRelOffset(buf, vgmOffGD3, headerSize+len(f.Data))
+	}
+	binary.LittleEndian.PutUint32(buf[vgmOffLoop:], 0)
+	binary.LittleEndian.PutUint32(buf[vgmOffLoopSamples:], 0)
+	if f.LoopOffset >= 0 {
+		putRelOffset(buf, vgmOffLoop, headerSize+f.LoopOffset)
+		binary.LittleEndian.PutUint32(buf[vgmOffLoopSamples:], f.LoopSamples)
+	}
+	if f.Version >= 0x110 {
+		binary.LittleEndian.PutUint16(buf[vgmOffFeedback:], f.Feedback)
+		buf[vgmOffShiftWidth] = f.ShiftWidth
+	}
+	if f.Version >= 0x151 {
+		buf[vgmOffFlags] = f.Flags
+	}
+	if f.Version >= 0x150 {
+		putRelOffset(buf, vgmOffData, headerSize)
+	}
+	return buf, nil
+}
+
+// WriteTo writes the encoded file to w, compressed if f.Compressed is set.
+func (f *VGMFile) WriteTo(w io.Writer) (int64, error) {
+	buf, err := f.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(buf)
+	return int64(n), err
+}
+
+// putRelOffset writes target as an offset relative to the field at pos.
+func putRelOffset(buf []byte, pos int, target int) {
+	binary.LittleEndian.PutUint32(buf[pos:], uint32(target-pos))
+}
+
+// VGMLogger records writes to an SN76489 with their input-clock timing and
+// produces a VGM file from them. It wraps the chip: route all Write and
+// Run/GenerateSamples/Clock calls through the logger so it can timestamp
+// each write.
+type VGMLogger struct {
+	chip *SN76489
+
+	clocks  uint64 // Input clocks elapsed since logging started
+	samples uint64 // 44.1 kHz samples already emitted as waits
+
+	data        []byte // Command stream (without the end marker)
+	loopOffset  int    // Offset into data of the loop point, -1 if unset
+	loopSamples uint64 // Sample position of the loop point
+	stereoUsed  bool   // A Game Gear stereo write was recorded
+	gd3         *GD3   // Metadata tag, nil for none
+	compressed  bool   // Write gzip-compressed VGZ output
+}
+
+// NewVGMLogger creates a logger that forwards to chip and records its writes.
+// Registers the chip already holds are recorded as writes at sample 0, so a
+// chip that is already playing is captured from its current state rather
+// than from power-on silence.
+func NewVGMLogger(chip *SN76489) *VGMLogger {
+	l := &VGMLogger{
+		chip:       chip,
+		loopOffset: -1,
+	}
+	l.logRegisters()
+	return l
+}
+
+// logRegisters records writes that bring a power-on chip to the wrapped
+// chip's registers and latch. Registers at their power-on value are
+// skipped. The latched register is written last so later data bytes reach
+// the same register on playback.
+func (l *VGMLogger) logRegisters() {
+	c := l.chip
+	var latched []uint8
+	add := func(ch int, volume, changed bool, cmds ...uint8) {
+		if ch == int(c.latchedChannel) && volume == (c.latchedType == 1) {
+			latched = cmds
+			return
+		}
+		if changed {
+			for _, cmd := range cmds {
+				l.data = append(l.data, vgmCmdPSGWrite, cmd)
+			}
+		}
+	}
+	for ch := 0; ch < 3; ch++ {
+		tone := EncodeTone(ch, c.toneReg[ch])
+		add(ch, false, c.toneReg[ch] != 0, tone[0], tone[1])
+	}
+	add(3, false, c.noiseReg != 0, EncodeNoise(c.noiseReg&0x04 != 0, c.noiseReg&0x03))
+	for ch := 0; ch < 4; ch++ {
+		add(ch, true, c.volume[ch] != 0x0F, EncodeVolume(ch, c.volume[ch]))
+	}
+
+	// The power-on latch is tone channel 0; skip it if nothing changed.
+	if c.latchedChannel != 0 || c.latchedType != 0 || c.toneReg[0] != 0 {
+		for _, cmd := range latched {
+			l.data = append(l.data, vgmCmdPSGWrite, cmd)
+		}
+	}
+}
+
+// Chip returns the wrapped chip.
+func (l *VGMLogger) Chip() *SN76489 {
+	return l.chip
+}
+
+// Write writes value to the chip and records it at the current clock position.
+func (l *VGMLogger) Write(value uint8) {
+	l.sync()
+	l.data = append(l.data, vgmCmdPSGWrite, value)
+	l.chip.Write(value)
+}
+
+// WriteStereo records a Game Gear stereo register (port 0x06) write at the
+// current clock position. The chip itself has no stereo register; apply the
+// panning to GetChannelBuffers output as usual.
+func (l *VGMLogger) WriteStereo(value uint8) {
+	l.sync()
+	l.data = append(l.data, vgmCmdGGStereo, value)
+	l.stereoUsed = true
+}
+
+// Clock advances the chip by one input clock cycle.
+func (l *VGMLogger) Clock() {
+	l.chip.Clock()
+	l.clocks++
+}
+
+// Run advances the chip by the given number of clocks. See SN76489.Run.
+func (l *VGMLogger) Run(clocks int) int {
+	l.clocks += uint64(clocks)
+	return l.chip.Run(clocks)
+}
+
+// GenerateSamples resets the chip's buffer and runs the given number of
+// clocks. See SN76489.GenerateSamples.
+func (l *VGMLogger) GenerateSamples(clocks int) int {
+	l.clocks += uint64(clocks)
+	return l.chip.GenerateSamples(clocks)
+}
+
+// ResetBuffer resets the chip's buffer position. See SN76489.ResetBuffer.
+func (l *VGMLogger) ResetBuffer() {
+	l.chip.ResetBuffer()
+}
+
+// MarkLoop sets the VGM loop point to the current clock position. Players
+// jump back here after reaching the end of the recording. Calling it again
+// moves the loop point.
+func (l *VGMLogger) MarkLoop() {
+	l.sync()
+	l.loopOffset = len(l.data)
+	l.loopSamples = l.samples
+}
+
+// ClearLoop removes the loop point so the VGM plays once.
+func (l *VGMLogger) ClearLoop() {
+	l.loopOffset = -1
+	l.loopSamples = 0
+}
+
+// TotalSamples returns the length of the recording so far in 44.1 kHz samples.
+func (l *VGMLogger) TotalSamples() uint64 {
+	return l.clocksToSamples(l.clocks)
+}
+
+// SetGD3 sets the metadata tag written after the command stream. Pass nil
+// to omit the tag.
+func (l *VGMLogger) SetGD3(tag *GD3) {
+	l.gd3 = tag
+}
+
+// SetCompressed selects gzip-compressed (.vgz) output from WriteTo and File.
+func (l *VGMLogger) SetCompressed(compressed bool) {
+	l.compressed = compressed
+}
+
+// File returns the recording so far as a VGMFile. Logging can continue
+// afterwards; a later call includes everything recorded so far.
+func (l *VGMLogger) File() *VGMFile {
+	l.sync()
+	config := l.chip.GetConfig()
+
+	data := make([]byte, len(l.data)+1)
+	copy(data, l.data)
+	data[len(l.data)] = vgmCmdEnd
+
+	f := &VGMFile{
+		Version:      vgmVersion,
+		ClockFreq:    l.chip.GetClockFreq(),
+		TotalSamples: uint32(l.samples),
+		LoopOffset:   -1,
+		Feedback:     config.WhiteNoiseTaps,
+		ShiftWidth:   uint8(config.LFSRBits),
+		Flags:        vgmFlags(config, l.stereoUsed),
+		Header:       make([]byte, vgmHeaderSize),
+		Data:         data,
+		GD3:          l.gd3,
+		Compressed:   l.compressed,
+	}
+	if l.loopOffset >= 0 {
+		f.LoopOffset = l.loopOffset
+		f.LoopSamples = uint32(l.samples - l.loopSamples)
+	}
+	return f
+}
+
+// WriteTo writes the recording as a VGM file to w.
+func (l *VGMLogger) WriteTo(w io.Writer) (int64, error) {
+	return l.File().WriteTo(w)
+}
+
+// vgmFlags returns the SN76489 flags header byte for a config.
+func vgmFlags(config Config, stereo bool) uint8 {
+	flags := uint8(vgmFlagNoClockDiv8)
+	if config.ToneZero == ToneZeroAs1024 {
+		flags |= vgmFlagFreq0Is0x400
+	}
+	if !stereo {
+		flags |= vgmFlagNoStereo
+	}
+	return flags
+}
+
+// sync emits wait commands so the command stream catches up with the
+// current clock position.
+func (l *VGMLogger) sync() {
+	target := l.clocksToSamples(l.clocks)
+	if target > l.samples {
+		l.data = appendVGMWait(l.data, target-l.samples)
+		l.samples = target
+	}
+}
+
+// clocksToSamples converts an input clock count to 44.1 kHz samples.
+// Computed from the absolute clock count so rounding never accumulates.
+func (l *VGMLogger) clocksToSamples(clocks uint64) uint64 {
+	freq := uint64(l.chip.GetClockFreq())
+	if freq == 0 {
+		return 0
+	}
+	return clocks * vgmSampleRate / freq
+}
+
+// appendVGMWait appends the shortest wait command sequence for n samples.
+func appendVGMWait(data []byte, n uint64) []byte {
+	for n > 0 {
+		switch {
+		case n == 735:
+			return append(data, vgmCmdWait735)
+		case n == 882:
+			return append(data, vgmCmdWait882)
+		case n <= 16:
+			return append(data, vgmCmdWaitN|uint8(n-1))
+		}
+		chunk := n
+		if chunk > 0xFFFF {
+			chunk = 0xFFFF
+		}
+		data = append(data, vgmCmdWait, uint8(chunk), uint8(chunk>>8))
+		n -= chunk
+	}
+	return data
+}
@@ -0,0 +1,467 @@
+package sn76489
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+// vgmCommand is one decoded command from a VGM command stream.
+type vgmCommand struct {
+	op    uint8
+	value uint8  // Data byte for 0x4F/0x50
+	wait  uint64 // Samples for wait commands
+}
+
+// decodeVGMCommands walks a logger-produced command stream up to the end marker.
+func decodeVGMCommands(t *testing.T, data []byte) []vgmCommand {
+	t.Helper()
+	var cmds []vgmCommand
+	for i := 0; i < len(data); {
+		op := data[i]
+		switch {
+		case op == vgmCmdPSGWrite || op == vgmCmdGGStereo:
+			cmds = append(cmds, vgmCommand{op: op, value: data[i+1]})
+			i += 2
+		case op == vgmCmdWait:
+			cmds = append(cmds, vgmCommand{op: op, wait: uint64(binary.LittleEndian.Uint16(data[i+1:]))})
+			i += 3
+		case op == vgmCmdWait735:
+			cmds = append(cmds, vgmCommand{op: op, wait: 735})
+			i++
+		case op == vgmCmdWait882:
+			cmds = append(cmds, vgmCommand{op: op, wait: 882})
+			i++
+		case op&0xF0 == vgmCmdWaitN:
+			cmds = append(cmds, vgmCommand{op: op, wait: uint64(op&0x0F) + 1})
+			i++
+		case op == vgmCmdEnd:
+			return cmds
+		default:
+			t.Fatalf("Unexpected VGM command 0x%02X at offset %d", op, i)
+		}
+	}
+	t.Fatal("VGM command stream has no end marker")
+	return nil
+}
+
+// TestVGMLogger_Header verifies the header reflects the chip's clock and Config.
+func TestVGMLogger_Header(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config Config
+		width  uint8
+		flags  uint8
+	}{
+		{"Sega", Sega, 16, vgmFlagNoClockDiv8 | vgmFlagNoStereo},
+		{"TI", TI, 15, vgmFlagNoClockDiv8 | vgmFlagNoStereo | vgmFlagFreq0Is0x400},
+	}
+
+	for _, tc := range testCases {
+		chip := New(3579545, 48000, 800, tc.config)
+		logger := NewVGMLogger(chip)
+		logger.Write(0x90)
+		logger.Run(3579545)
+
+		var out bytes.Buffer
+		if _, err := logger.WriteTo(&out); err != nil {
+			t.Fatal(err)
+		}
+		file := out.Bytes()
+
+		if string(file[0:4]) != "Vgm " {
+			t.Errorf("%s: bad magic %q", tc.name, file[0:4])
+		}
+		if got := binary.LittleEndian.Uint32(file[0x04:]); int(got) != len(file)-4 {
+			t.Errorf("%s: EOF offset: expected %d, got %d", tc.name, len(file)-4, got)
+		}
+		if got := binary.LittleEndian.Uint32(file[0x08:]); got != vgmVersion {
+			t.Errorf("%s: version: expected 0x%X, got 0x%X", tc.name, vgmVersion, got)
+		}
+		if got := binary.LittleEndian.Uint32(file[0x0C:]); got != 3579545 {
+			t.Errorf("%s: clock: expected 3579545, got %d", tc.name, got)
+		}
+		if got := binary.LittleEndian.Uint32(file[0x18:]); got != 44100 {
+			t.Errorf("%s: total samples: expected 44100, got %d", tc.name, got)
+		}
+		if got := binary.LittleEndian.Uint32(file[0x1C:]); got != 0 {
+			t.Errorf("%s: loop offset should be 0 without a loop, got %d", tc.name, got)
+		}
+		if got := binary.LittleEndian.Uint16(file[0x28:]); got != tc.config.WhiteNoiseTaps {
+			t.Errorf("%s: feedback: expected 0x%04X, got 0x%04X", tc.name, tc.config.WhiteNoiseTaps, got)
+		}
+		if file[0x2A] != tc.width {
+			t.Errorf("%s: shift width: expected %d, got %d", tc.name, tc.width, file[0x2A])
+		}
+		if file[0x2B] != tc.flags {
+			t.Errorf("%s: flags: expected 0x%02X, got 0x%02X", tc.name, tc.flags, file[0x2B])
+		}
+		if got := binary.LittleEndian.Uint32(file[0x34:]); got+0x34 != vgmHeaderSize {
+			t.Errorf("%s: data offset: expected 0x%X, got 0x%X", tc.name, vgmHeaderSize, got+0x34)
+		}
+		if file[len(file)-1] != vgmCmdEnd {
+			t.Errorf("%s: last byte should be end marker, got 0x%02X", tc.name, file[len(file)-1])
+		}
+	}
+}
+
+// TestVGMLogger_WriteTiming verifies writes land at the correct 44.1 kHz
+// sample positions and that waits add up to the total length.
+func TestVGMLogger_WriteTiming(t *testing.T) {
+	chip := New(3579545, 48000, 1000, Sega)
+	logger := NewVGMLogger(chip)
+
+	// One NTSC frame is 59659 clocks = 735 samples at 44.1 kHz (rounded down).
+	logger.Write(0x9F)
+	logger.GenerateSamples(59659)
+	logger.Write(0x90)
+	logger.ResetBuffer()
+	logger.Run(1000)
+	logger.WriteStereo(0xF0)
+	logger.Run(1000)
+
+	var out bytes.Buffer
+	if _, err := logger.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	cmds := decodeVGMCommands(t, out.Bytes()[vgmHeaderSize:])
+
+	var pos uint64
+	var writes []uint64
+	var values []uint8
+	for _, c := range cmds {
+		if c.wait > 0 {
+			pos += c.wait
+			continue
+		}
+		writes = append(writes, pos)
+		values = append(values, c.value)
+	}
+
+	expectedPos := []uint64{0, 59659 * 44100 / 3579545, 60659 * 44100 / 3579545}
+	expectedVal := []uint8{0x9F, 0x90, 0xF0}
+	if len(writes) != len(expectedPos) {
+		t.Fatalf("Expected %d writes, got %d", len(expectedPos), len(writes))
+	}
+	for i := range writes {
+		if writes[i] != expectedPos[i] || values[i] != expectedVal[i] {
+			t.Errorf("Write %d: expected 0x%02X at sample %d, got 0x%02X at sample %d",
+				i, expectedVal[i], expectedPos[i], values[i], writes[i])
+		}
+	}
+
+	total := uint64(61659) * 44100 / 3579545
+	if pos != total {
+		t.Errorf("Waits sum to %d samples, expected %d", pos, total)
+	}
+	if logger.TotalSamples() != total {
+		t.Errorf("TotalSamples: expected %d, got %d", total, logger.TotalSamples())
+	}
+	if out.Bytes()[0x2B]&vgmFlagNoStereo != 0 {
+		t.Error("Stereo flag should be enabled after a stereo write")
+	}
+
+	// The chip itself must have received the PSG writes.
+	if got := chip.GetVolume(0); got != 0x00 {
+		t.Errorf("Chip volume 0: expected 0x00, got 0x%02X", got)
+	}
+}
+
+// TestVGMLogger_Loop verifies loop offset and loop length header fields.
+func TestVGMLogger_Loop(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	logger := NewVGMLogger(chip)
+
+	logger.Write(0x90)
+	logger.Run(3579545 / 10)
+	logger.MarkLoop()
+	logger.Write(0x9F)
+	logger.Run(3579545 / 10)
+
+	var out bytes.Buffer
+	if _, err := logger.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	file := out.Bytes()
+
+	loopOffset := int(binary.LittleEndian.Uint32(file[0x1C:])) + 0x1C
+	if loopOffset <= vgmHeaderSize || loopOffset >= len(file) {
+		t.Fatalf("Loop offset 0x%X out of range", loopOffset)
+	}
+	if file[loopOffset] != vgmCmdPSGWrite || file[loopOffset+1] != 0x9F {
+		t.Errorf("Loop point should start at the 0x9F write, got 0x%02X 0x%02X",
+			file[loopOffset], file[loopOffset+1])
+	}
+
+	total := binary.LittleEndian.Uint32(file[0x18:])
+	loopSamples := binary.LittleEndian.Uint32(file[0x20:])
+	firstHalf := uint32(3579545 / 10 * 44100 / 3579545)
+	if loopSamples != total-firstHalf {
+		t.Errorf("Loop samples: expected %d, got %d", total-firstHalf, loopSamples)
+	}
+
+	logger.ClearLoop()
+	out.Reset()
+	if _, err := logger.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	if got := binary.LittleEndian.Uint32(out.Bytes()[0x1C:]); got != 0 {
+		t.Errorf("Loop offset after ClearLoop: expected 0, got %d", got)
+	}
+}
+
+// TestAppendVGMWait verifies wait encoding uses the short forms and splits
+// long waits.
+func TestAppendVGMWait(t *testing.T) {
+	testCases := []struct {
+		n        uint64
+		expected []byte
+	}{
+		{1, []byte{0x70}},
+		{16, []byte{0x7F}},
+		{17, []byte{0x61, 17, 0}},
+		{735, []byte{0x62}},
+		{882, []byte{0x63}},
+		{0xFFFF, []byte{0x61, 0xFF, 0xFF}},
+		{0x10000, []byte{0x61, 0xFF, 0xFF, 0x70}},
+	}
+
+	for _, tc := range testCases {
+		got := appendVGMWait(nil, tc.n)
+		if !bytes.Equal(got, tc.expected) {
+			t.Errorf("Wait %d: expected % X, got % X", tc.n, tc.expected, got)
+		}
+	}
+}
+
+// TestParseVGM_LoggerRoundTrip verifies ParseVGM reads back the logger's
+// header fields, loop point and command stream.
+func TestParseVGM_LoggerRoundTrip(t *testing.T) {
+	chip := New(3579545, 48000, 800, TI)
+	logger := NewVGMLogger(chip)
+	logger.Write(0x90)
+	logger.Run(50000)
+	logger.MarkLoop()
+	logger.Write(0x9F)
+	logger.Run(50000)
+
+	var out bytes.Buffer
+	if _, err := logger.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	f, err := ParseVGM(out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.ClockFreq != 3579545 {
+		t.Errorf("ClockFreq: expected 3579545, got %d", f.ClockFreq)
+	}
+	if f.Config() != TI {
+		t.Errorf("Config: expected %+v, got %+v", TI, f.Config())
+	}
+	if uint64(f.TotalSamples) != logger.TotalSamples() {
+		t.Errorf("TotalSamples: expected %d, got %d", logger.TotalSamples(), f.TotalSamples)
+	}
+	if f.LoopOffset < 0 || f.Data[f.LoopOffset] != vgmCmdPSGWrite || f.Data[f.LoopOffset+1] != 0x9F {
+		t.Errorf("Loop offset %d does not point at the 0x9F write", f.LoopOffset)
+	}
+	if f.GD3 != nil {
+		t.Error("GD3 should be nil when the logger has no tag")
+	}
+
+	// Re-encoding an unmodified file reproduces it exactly.
+	again, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, out.Bytes()) {
+		t.Error("MarshalBinary of parsed file differs from the original")
+	}
+}
+
+// TestParseVGM_Invalid verifies malformed files are rejected.
+func TestParseVGM_Invalid(t *testing.T) {
+	if _, err := ParseVGM([]byte("Vgm ")); err == nil {
+		t.Error("ParseVGM should reject a short file")
+	}
+
+	chip := New(3579545, 48000, 800, Sega)
+	var out bytes.Buffer
+	if _, err := NewVGMLogger(chip).WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	bad := append([]byte{}, out.Bytes()...)
+	copy(bad, "Vgx ")
+	if _, err := ParseVGM(bad); err == nil {
+		t.Error("ParseVGM should reject bad magic")
+	}
+
+	bad = append([]byte{}, out.Bytes()...)
+	binary.LittleEndian.PutUint32(bad[vgmOffData:], 0x10000)
+	if _, err := ParseVGM(bad); err == nil {
+		t.Error("ParseVGM should reject an out-of-range data offset")
+	}
+
+	bad = append([]byte{}, out.Bytes()...)
+	binary.LittleEndian.PutUint32(bad[vgmOffLoop:], 0x10000)
+	if _, err := ParseVGM(bad); err == nil {
+		t.Error("ParseVGM should reject an out-of-range loop offset")
+	}
+}
+
+// TestVGZ_RoundTrip verifies compressed output is gzip, is detected by
+// ParseVGM/ReadVGM, and decompresses to the same file as uncompressed output.
+func TestVGZ_RoundTrip(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	logger := NewVGMLogger(chip)
+	logger.SetGD3(&GD3{TrackName: "compressed"})
+	logger.Write(0x90)
+	logger.Run(100000)
+
+	var plain bytes.Buffer
+	if _, err := logger.WriteTo(&plain); err != nil {
+		t.Fatal(err)
+	}
+	logger.SetCompressed(true)
+	var packed bytes.Buffer
+	if _, err := logger.WriteTo(&packed); err != nil {
+		t.Fatal(err)
+	}
+
+	if !isGzip(packed.Bytes()) {
+		t.Fatal("Compressed output should start with the gzip magic")
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(packed.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unpacked, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(unpacked, plain.Bytes()) {
+		t.Error("Decompressed VGZ differs from uncompressed VGM output")
+	}
+
+	f, err := ReadVGM(bytes.NewReader(packed.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.Compressed {
+		t.Error("Compressed should be set after parsing VGZ input")
+	}
+	if f.GD3 == nil || f.GD3.TrackName != "compressed" {
+		t.Errorf("GD3 not read from VGZ: %+v", f.GD3)
+	}
+
+	// Writing back keeps compression; clearing it produces the plain file.
+	again, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isGzip(again) {
+		t.Error("Re-encoded VGZ should stay compressed")
+	}
+	f.Compressed = false
+	again, err = f.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, plain.Bytes()) {
+		t.Error("Uncompressed re-encode differs from original VGM")
+	}
+
+	plainFile, err := ParseVGM(plain.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plainFile.Compressed {
+		t.Error("Compressed should not be set for plain VGM input")
+	}
+}
+
+// TestVGZ_Corrupt verifies a truncated gzip stream is reported as an error.
+func TestVGZ_Corrupt(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	logger := NewVGMLogger(chip)
+	logger.SetCompressed(true)
+	logger.Run(100000)
+
+	var packed bytes.Buffer
+	if _, err := logger.WriteTo(&packed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseVGM(packed.Bytes()[:packed.Len()/2]); err == nil {
+		t.Error("ParseVGM should reject a truncated VGZ")
+	}
+}
+
+// TestVGMFile_ConfigDefaults verifies unset or out-of-range noise fields
+// fall back to the Sega LFSR, so noise from such files is audible.
+func TestVGMFile_ConfigDefaults(t *testing.T) {
+	tests := []struct {
+		file VGMFile
+		want Config
+	}{
+		{VGMFile{}, Sega},
+		{VGMFile{ShiftWidth: 16}, Sega},
+		{VGMFile{Feedback: 0x0003, ShiftWidth: 20}, Sega},
+		{VGMFile{Flags: vgmFlagFreq0Is0x400}, Config{LFSRBits: 16, WhiteNoiseTaps: 0x0009, ToneZero: ToneZeroAs1024}},
+		{VGMFile{Feedback: 0x0003, ShiftWidth: 15, Flags: vgmFlagFreq0Is0x400}, TI},
+	}
+	for _, tt := range tests {
+		if got := tt.file.Config(); got != tt.want {
+			t.Errorf("%+v: expected %+v, got %+v", tt.file, tt.want, got)
+		}
+	}
+
+	var f VGMFile
+	chip := New(3579545, 44100, 800, f.Config())
+	chip.Write(0xE4)
+	chip.Write(0xF0)
+	chip.GenerateSamples(59659)
+	bufs, n := chip.GetChannelBuffers()
+	nonzero := 0
+	for _, v := range bufs[3][:n] {
+		if v != 0 {
+			nonzero++
+		}
+	}
+	if nonzero == 0 {
+		t.Error("White noise from a header without noise fields should be audible")
+	}
+}
+
+// TestVGMLogger_WrapsPlayingChip verifies a logger wrapping a chip that is
+// already playing records its registers and latch at sample 0, so playback
+// reproduces the chip's state.
+func TestVGMLogger_WrapsPlayingChip(t *testing.T) {
+	if l := NewVGMLogger(New(3579545, 44100, 800, Sega)); len(l.File().Data) != 1 {
+		t.Errorf("Power-on chip: expected no initial writes, got % X", l.File().Data)
+	}
+
+	chip := New(3579545, 44100, 800, Sega)
+	writeTone(chip, 1, 0x123)
+	writeTone(chip, 2, 0x0FE)
+	chip.Write(0xE5) // White noise, rate 1
+	chip.Write(0xF2) // Noise volume 2
+	chip.Write(0xC7) // Latch tone 2, changing only its low nibble
+	chip.GenerateSamples(10000)
+
+	logger := NewVGMLogger(chip)
+	logger.Write(0x10) // Data byte for the latched tone 2
+	logger.GenerateSamples(10000)
+
+	replay := New(3579545, 44100, 800, Sega)
+	NewVGMPlayer(logger.File(), replay).GenerateSamples(10000)
+	if got, want := replay.GetRegisters(), chip.GetRegisters(); got != want {
+		t.Errorf("Replayed registers: expected %+v, got %+v", want, got)
+	}
+	if got := replay.GetToneReg(2); got != 0x107 {
+		t.Errorf("Data byte after the initial writes: expected tone 2 = 0x107, got 0x%X", got)
+	}
+}
@@ -0,0 +1,206 @@
+package sn76489
+
+// VGM commands the player acts on beyond those the logger writes.
+const (
+	vgmCmdPSGWrite2  = 0x30 // Second SN76489 write (dual-chip files), ignored
+	vgmCmdDataBlock  = 0x67 // Data block: 0x67 0x66 tt ssssssss <data>
+	vgmCmdDACWaitMin = 0x80 // YM2612 DAC write + wait n: 0x8n
+	vgmCmdDACWaitMax = 0x8F
+)
+
+// VGMPlayer plays the SN76489 part of a VGM file into a chip. Commands for
+// other chips are skipped. Writes are applied at their exact clock position
+// within each Run, so the chip's output matches a cycle-accurate host.
+type VGMPlayer struct {
+	file *VGMFile
+	chip *SN76489
+
+	pos       int    // Offset into file.Data of the next command
+	samplePos uint64 // 44.1 kHz sample position of the next command
+	clocks    uint64 // Chip clocks run since playback started
+
+	maxLoops  int    // Loop passes to play before ending, -1 = forever
+	loops     int    // Loop passes completed
+	loopStart uint64 // samplePos at the most recent jump to the loop point
+	ended     bool   // Reached the end of the data (after all loops)
+	stereo    uint8
+}
+
+// NewVGMPlayer creates a player for f driving chip. The chip should be
+// constructed with f's clock and Config (see VGMFile.Config). By default the
+// loop section, if any, is not repeated. A file with no SN76489 (ClockFreq
+// 0) has no timing for its commands, so the player starts out ended.
+func NewVGMPlayer(f *VGMFile, chip *SN76489) *VGMPlayer {
+	return &VGMPlayer{
+		file:   f,
+		chip:   chip,
+		ended:  f.ClockFreq == 0,
+		stereo: 0xFF,
+	}
+}
+
+// SetLoops sets how many times the loop section is repeated after the first
+// pass before playback ends. -1 loops forever. Has no effect on files
+// without a loop point.
+func (p *VGMPlayer) SetLoops(loops int) {
+	p.maxLoops = loops
+}
+
+// Loops returns the number of loop repeats completed so far.
+func (p *VGMPlayer) Loops() int {
+	return p.loops
+}
+
+// Ended reports whether playback has reached the end of the data.
+func (p *VGMPlayer) Ended() bool {
+	return p.ended
+}
+
+// Stereo returns the last Game Gear stereo register value (0xFF, all
+// channels on both sides, until the file writes one).
+func (p *VGMPlayer) Stereo() uint8 {
+	return p.stereo
+}
+
+// Chip returns the chip being driven.
+func (p *VGMPlayer) Chip() *SN76489 {
+	return p.chip
+}
+
+// GenerateSamples resets the chip's buffer and plays the given number of
+// chip clocks. Returns the number of samples dropped due to buffer overflow.
+func (p *VGMPlayer) GenerateSamples(clocks int) int {
+	p.chip.ResetBuffer()
+	return p.Run(clocks)
+}
+
+// Run plays the given number of chip clocks, accumulating samples into the
+// chip's buffer like SN76489.Run. After the end of the data the chip keeps
+// running with its last register state. Returns the number of samples
+// dropped due to buffer overflow.
+func (p *VGMPlayer) Run(clocks int) int {
+	target := p.clocks + uint64(clocks)
+	dropped := 0
+	for !p.ended {
+		next := p.sampleToClock(p.samplePos)
+		if next > target {
+			break
+		}
+		if next > p.clocks {
+			dropped += p.chip.Run(int(next - p.clocks))
+			p.clocks = next
+		}
+		p.step()
+	}
+	if target > p.clocks {
+		dropped += p.chip.Run(int(target - p.clocks))
+		p.clocks = target
+	}
+	return dropped
+}
+
+// sampleToClock converts a 44.1 kHz sample position to chip clocks.
+func (p *VGMPlayer) sampleToClock(sample uint64) uint64 {
+	return sample * uint64(p.file.ClockFreq) / vgmSampleRate
+}
+
+// step executes one command, advancing samplePos for waits.
+func (p *VGMPlayer) step() {
+	data := p.file.Data
+	if p.pos >= len(data) {
+		p.endOfData()
+		return
+	}
+
+	op := data[p.pos]
+	size := vgmCommandSize(data[p.pos:])
+	if size == 0 || p.pos+size > len(data) {
+		// Unknown or truncated command: nothing after it can be trusted.
+		p.endOfData()
+		return
+	}
+	arg := data[p.pos+1:]
+
+	switch {
+	case op == vgmCmdPSGWrite:
+		p.chip.Write(arg[0])
+	case op == vgmCmdGGStereo:
+		p.stereo = arg[0]
+	case op == vgmCmdWait:
+		p.samplePos += uint64(arg[0]) | uint64(arg[1])<<8
+	case op == vgmCmdWait735:
+		p.samplePos += 735
+	case op == vgmCmdWait882:
+		p.samplePos += 882
+	case op&0xF0 == vgmCmdWaitN:
+		p.samplePos += uint64(op&0x0F) + 1
+	case op >= vgmCmdDACWaitMin && op <= vgmCmdDACWaitMax:
+		p.samplePos += uint64(op & 0x0F)
+	case op == vgmCmdEnd:
+		p.endOfData()
+		return
+	}
+	p.pos += size
+}
+
+// endOfData jumps to the loop point, or ends playback when there is none or
+// all loop repeats have been played.
+func (p *VGMPlayer) endOfData() {
+	if p.file.LoopOffset < 0 || (p.maxLoops >= 0 && p.loops >= p.maxLoops) {
+		p.ended = true
+		return
+	}
+	if p.loops > 0 && p.samplePos == p.loopStart {
+		// A loop section with no waits would spin forever within one Run.
+		p.ended = true
+		return
+	}
+	p.loops++
+	p.loopStart = p.samplePos
+	p.pos = p.file.LoopOffset
+}
+
+// vgmCommandSize returns the length in bytes of the command at the start of
+// data, including the command byte, or 0 if it is unknown.
+func vgmCommandSize(data []byte) int {
+	op := data[0]
+	switch {
+	case op >= vgmCmdPSGWrite2 && op <= 0x3F:
+		return 2
+	case op >= 0x40 && op <= 0x4E:
+		return 3
+	case op == vgmCmdGGStereo || op == vgmCmdPSGWrite:
+		return 2
+	case op >= 0x51 && op <= 0x5F:
+		return 3
+	case op == vgmCmdWait:
+		return 3
+	case op == vgmCmdWait735 || op == vgmCmdWait882 || op == vgmCmdEnd:
+		return 1
+	case op == vgmCmdDataBlock:
+		if len(data) < 7 {
+			return 0
+		}
+		size := int(uint32(data[3]) | uint32(data[4])<<8 | uint32(data[5])<<16 | uint32(data[6]&0x7F)<<24)
+		return 7 + size
+	case op == 0x68:
+		return 12
+	case op >= 0x70 && op <= 0x8F:
+		return 1
+	case op == 0x90 || op == 0x91 || op == 0x95:
+		return 5
+	case op == 0x92:
+		return 6
+	case op == 0x93:
+		return 11
+	case op == 0x94:
+		return 2
+	case op >= 0xA0 && op <= 0xBF:
+		return 3
+	case op >= 0xC0 && op <= 0xDF:
+		return 4
+	case op >= 0xE0:
+		return 5
+	}
+	return 0
+}
@@ -0,0 +1,191 @@
+package sn76489
+
+import (
+	"bytes"
+	"testing"
+)
+
+// logScript records a short tune through a VGMLogger and returns the file.
+// The clock is 16 * 44100 Hz so every 16-clock write position lands exactly
+// on a VGM sample and playback can reproduce the output bit for bit.
+func logScript(t *testing.T, chip *SN76489, loop bool) *VGMFile {
+	t.Helper()
+	logger := NewVGMLogger(chip)
+	logger.Write(0x85) // Channel 0 tone low nibble = 5
+	logger.Write(0x02) // Tone = 0x25
+	logger.Write(0x90) // Channel 0 max volume
+	logger.Run(16 * 300)
+	if loop {
+		logger.MarkLoop()
+	}
+	logger.Write(0xE4) // White noise
+	logger.Write(0xF2) // Noise volume 2
+	logger.Run(16 * 500)
+	logger.Write(0x9F) // Channel 0 silent
+	logger.Run(16 * 200)
+
+	var out bytes.Buffer
+	if _, err := logger.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	f, err := ParseVGM(out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// TestVGMPlayer_ReproducesLoggedOutput verifies playing back a logged VGM
+// produces exactly the output the original chip produced.
+func TestVGMPlayer_ReproducesLoggedOutput(t *testing.T) {
+	const clock = 16 * vgmSampleRate
+	orig := New(clock, 44100, 2000, Sega)
+	orig.ResetBuffer()
+	f := logScript(t, orig, false)
+	origBuf, origCount := orig.GetBuffer()
+
+	chip := New(clock, 44100, 2000, f.Config())
+	player := NewVGMPlayer(f, chip)
+	player.GenerateSamples(16 * 1000)
+	buf, count := chip.GetBuffer()
+
+	if count != origCount {
+		t.Fatalf("Sample count: expected %d, got %d", origCount, count)
+	}
+	for i := 0; i < count; i++ {
+		if buf[i] != origBuf[i] {
+			t.Fatalf("Sample %d: expected %f, got %f", i, origBuf[i], buf[i])
+		}
+	}
+	if !player.Ended() {
+		t.Error("Player should have ended after the full length")
+	}
+}
+
+// TestVGMPlayer_SplitRuns verifies splitting playback across many Run calls
+// gives the same output as one call.
+func TestVGMPlayer_SplitRuns(t *testing.T) {
+	const clock = 16 * vgmSampleRate
+	f := logScript(t, New(clock, 44100, 2000, Sega), false)
+
+	whole := New(clock, 44100, 2000, Sega)
+	NewVGMPlayer(f, whole).GenerateSamples(16 * 1000)
+	wholeBuf, wholeCount := whole.GetBuffer()
+
+	split := New(clock, 44100, 2000, Sega)
+	player := NewVGMPlayer(f, split)
+	split.ResetBuffer()
+	for i := 0; i < 16*1000; i += 333 {
+		n := 333
+		if i+n > 16*1000 {
+			n = 16*1000 - i
+		}
+		player.Run(n)
+	}
+	splitBuf, splitCount := split.GetBuffer()
+
+	if wholeCount != splitCount {
+		t.Fatalf("Sample count: whole=%d, split=%d", wholeCount, splitCount)
+	}
+	for i := 0; i < wholeCount; i++ {
+		if wholeBuf[i] != splitBuf[i] {
+			t.Fatalf("Sample %d: whole=%f, split=%f", i, wholeBuf[i], splitBuf[i])
+		}
+	}
+}
+
+// TestVGMPlayer_Loops verifies the loop section repeats SetLoops times.
+func TestVGMPlayer_Loops(t *testing.T) {
+	const clock = 16 * vgmSampleRate
+	f := logScript(t, New(clock, 44100, 2000, Sega), true)
+	if f.LoopOffset < 0 || f.LoopSamples != 700 {
+		t.Fatalf("Expected a 700-sample loop, got offset=%d samples=%d", f.LoopOffset, f.LoopSamples)
+	}
+
+	chip := New(clock, 44100, 5000, Sega)
+	player := NewVGMPlayer(f, chip)
+	player.SetLoops(2)
+
+	// Intro (300) + first pass of loop (700) + 2 repeats (1400) = 2400 samples.
+	player.GenerateSamples(16 * 2399)
+	if player.Ended() {
+		t.Error("Player ended before the final loop finished")
+	}
+	player.Run(16 * 2)
+	if !player.Ended() {
+		t.Error("Player should end after 2 loop repeats")
+	}
+	if player.Loops() != 2 {
+		t.Errorf("Loops: expected 2, got %d", player.Loops())
+	}
+}
+
+// TestVGMPlayer_InfiniteLoopWithoutWaits verifies a loop section with no
+// waits ends playback instead of hanging.
+func TestVGMPlayer_InfiniteLoopWithoutWaits(t *testing.T) {
+	f := &VGMFile{
+		ClockFreq:  3579545,
+		LoopOffset: 0,
+		Data:       []byte{vgmCmdPSGWrite, 0x90, vgmCmdEnd},
+	}
+	chip := New(3579545, 44100, 800, Sega)
+	player := NewVGMPlayer(f, chip)
+	player.SetLoops(-1)
+	player.GenerateSamples(1000)
+	if !player.Ended() {
+		t.Error("Player should end on a loop with no waits")
+	}
+}
+
+// TestVGMPlayer_NoPSG verifies a file without an SN76489 ends immediately
+// instead of looping forever at clock 0.
+func TestVGMPlayer_NoPSG(t *testing.T) {
+	f := &VGMFile{
+		LoopOffset: 0,
+		Data:       []byte{0x52, 0x28, 0xF0, vgmCmdWait735, vgmCmdEnd},
+	}
+	chip := New(3579545, 44100, 800, Sega)
+	player := NewVGMPlayer(f, chip)
+	player.SetLoops(-1)
+	player.GenerateSamples(1000)
+	if !player.Ended() {
+		t.Error("Player should end on a file with no SN76489")
+	}
+	if _, n := chip.GetBuffer(); n == 0 {
+		t.Error("Chip should keep running after the player ends")
+	}
+}
+
+// TestVGMPlayer_SkipsOtherChips verifies commands for other chips are skipped
+// with the right lengths and YM2612 DAC waits advance time.
+func TestVGMPlayer_SkipsOtherChips(t *testing.T) {
+	f := &VGMFile{
+		ClockFreq:  16 * vgmSampleRate,
+		LoopOffset: -1,
+		Data: []byte{
+			0x52, 0x28, 0xF0, // YM2612 port 0 write
+			0x67, 0x66, 0x00, 0x02, 0x00, 0x00, 0x00, 0xAA, 0xBB, // Data block
+			0x4F, 0x0F, // GG stereo
+			0x85, // YM2612 DAC write + wait 5
+			vgmCmdPSGWrite, 0x90,
+			vgmCmdEnd,
+		},
+	}
+	chip := New(16*vgmSampleRate, 44100, 800, Sega)
+	player := NewVGMPlayer(f, chip)
+
+	player.GenerateSamples(16*5 - 1)
+	if chip.GetVolume(0) != 0x0F {
+		t.Error("PSG write should not have happened before the DAC wait elapsed")
+	}
+	player.Run(1)
+	if chip.GetVolume(0) != 0x00 {
+		t.Errorf("PSG write after skipped commands: expected volume 0, got 0x%02X", chip.GetVolume(0))
+	}
+	if player.Stereo() != 0x0F {
+		t.Errorf("Stereo: expected 0x0F, got 0x%02X", player.Stereo())
+	}
+	if !player.Ended() {
+		t.Error("Player should have reached the end marker")
+	}
+}
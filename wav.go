@@ -0,0 +1,272 @@
+package sn76489
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// WAVFormat selects the sample encoding written by WAVWriter.
+type WAVFormat int
+
+const (
+	WAVFloat32 WAVFormat = iota // 32-bit IEEE float, written as-is
+	WAVInt16                    // 16-bit signed PCM, clamped to [-1, 1]
+)
+
+// WAV format tags (fmt chunk).
+const (
+	wavTagPCM   = 0x0001
+	wavTagFloat = 0x0003
+)
+
+// WAVWriter streams float32 samples to a WAV file. The header is written
+// up front with zero sizes and patched by Close, so the destination must be
+// seekable. The WAV file starts at the destination's position when the
+// writer is created, so it can be embedded after other data.
+type WAVWriter struct {
+	w          io.WriteSeeker
+	format     WAVFormat
+	channels   int
+	sampleRate int
+
+	start      int64  // Destination offset of the header
+	headerSize int    // Bytes before the sample data
+	dataBytes  uint64 // Sample data bytes written so far
+	scratch    []byte // Reused encode buffer
+	closed     bool
+}
+
+// NewWAVWriter writes a WAV header to w and returns a writer for samples
+// with the given rate, channel count (1 = mono, 2 = stereo) and format.
+func NewWAVWriter(w io.WriteSeeker, sampleRate int, channels int, format WAVFormat) (*WAVWriter, error) {
+	if channels < 1 {
+		return nil, errors.New("sn76489: WAV channel count must be at least 1")
+	}
+	if sampleRate < 1 {
+		return nil, errors.New("sn76489: WAV sample rate must be positive")
+	}
+	if format != WAVFloat32 && format != WAVInt16 {
+		return nil, errors.New("sn76489: unknown WAV format")
+	}
+
+	start, err := w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	ww := &WAVWriter{
+		w:          w,
+		start:      start,
+		format:     format,
+		channels:   channels,
+		sampleRate: sampleRate,
+	}
+	header := ww.header()
+	ww.headerSize = len(header)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return ww, nil
+}
+
+// bytesPerSample returns the encoded size of one sample.
+func (w *WAVWriter) bytesPerSample() int {
+	if w.format == WAVInt16 {
+		return 2
+	}
+	return 4
+}
+
+// header builds the RIFF header for the current data size. Float files get
+// the extended fmt chunk and the fact chunk required for non-PCM formats.
+func (w *WAVWriter) header() []byte {
+	bps := w.bytesPerSample()
+	blockAlign := w.channels * bps
+	frames := w.dataBytes / uint64(blockAlign)
+
+	var h []byte
+	h = append(h, "RIFF"...)
+	h = binary.LittleEndian.AppendUint32(h, 0) // Patched below
+	h = append(h, "WAVE"...)
+
+	h = append(h, "fmt "...)
+	if w.format == WAVFloat32 {
+		h = binary.LittleEndian.AppendUint32(h, 18)
+		h = binary.LittleEndian.AppendUint16(h, wavTagFloat)
+	} else {
+		h = binary.LittleEndian.AppendUint32(h, 16)
+		h = binary.LittleEndian.AppendUint16(h, wavTagPCM)
+	}
+	h = binary.LittleEndian.AppendUint16(h, uint16(w.channels))
+	h = binary.LittleEndian.AppendUint32(h, uint32(w.sampleRate))
+	h = binary.LittleEndian.AppendUint32(h, uint32(w.sampleRate*blockAlign))
+	h = binary.LittleEndian.AppendUint16(h, uint16(blockAlign))
+	h = binary.LittleEndian.AppendUint16(h, uint16(bps*8))
+	if w.format == WAVFloat32 {
+		h = binary.LittleEndian.AppendUint16(h, 0) // cbSize
+		h = append(h, "fact"...)
+		h = binary.LittleEndian.AppendUint32(h, 4)
+		h = binary.LittleEndian.AppendUint32(h, uint32(frames))
+	}
+
+	h = append(h, "data"...)
+	h = binary.LittleEndian.AppendUint32(h, uint32(w.dataBytes))
+
+	riffSize := uint64(len(h)) - 8 + w.dataBytes + w.dataBytes&1
+	binary.LittleEndian.PutUint32(h[4:], uint32(riffSize))
+	return h
+}
+
+// WriteSamples writes interleaved samples (one value per channel per frame).
+// len(samples) must be a multiple of the channel count.
+func (w *WAVWriter) WriteSamples(samples []float32) error {
+	if w.closed {
+		return errors.New("sn76489: write to closed WAV writer")
+	}
+	if len(samples)%w.channels != 0 {
+		return errors.New("sn76489: WAV sample count not a multiple of channel count")
+	}
+
+	bps := w.bytesPerSample()
+	size := len(samples) * bps
+	if w.dataBytes+uint64(size) > math.MaxUint32-uint64(w.headerSize) {
+		return errors.New("sn76489: WAV file exceeds 4 GiB")
+	}
+	if cap(w.scratch) < size {
+		w.scratch = make([]byte, size)
+	}
+	buf := w.scratch[:size]
+
+	for i, v := range samples {
+		if w.format == WAVInt16 {
+			binary.LittleEndian.PutUint16(buf[i*2:], uint16(floatToInt16(v)))
+		} else {
+			binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(v))
+		}
+	}
+	n, err := w.w.Write(buf)
+	w.dataBytes += uint64(n)
+	return err
+}
+
+// WriteStereo interleaves left and right into a stereo file. Both slices
+// must have the same length.
+func (w *WAVWriter) WriteStereo(left, right []float32) error {
+	if w.channels != 2 {
+		return errors.New("sn76489: WriteStereo requires a 2-channel WAV writer")
+	}
+	if len(left) != len(right) {
+		return errors.New("sn76489: WriteStereo channel lengths differ")
+	}
+
+	frames := make([]float32, len(left)*2)
+	for i := range left {
+		frames[i*2] = left[i]
+		frames[i*2+1] = right[i]
+	}
+	return w.WriteSamples(frames)
+}
+
+// WriteBuffer writes the chip's mixed output (GetBuffer). In a stereo file
+// the mono mix is written to both channels.
+func (w *WAVWriter) WriteBuffer(chip *SN76489) error {
+	buf, count := chip.GetBuffer()
+	switch w.channels {
+	case 1:
+		return w.WriteSamples(buf[:count])
+	case 2:
+		return w.WriteStereo(buf[:count], buf[:count])
+	}
+	return errors.New("sn76489: WriteBuffer requires a mono or stereo WAV writer")
+}
+
+// Close pads the data chunk to an even length and patches the header sizes.
+// It leaves the destination positioned just after the WAV data and does not
+// close it.
+func (w *WAVWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if w.dataBytes&1 != 0 {
+		if _, err := w.w.Write([]byte{0}); err != nil {
+			return err
+		}
+	}
+	if _, err := w.w.Seek(w.start, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := w.w.Write(w.header()); err != nil {
+		return err
+	}
+	end := w.start + int64(w.headerSize) + int64(w.dataBytes+w.dataBytes&1)
+	_, err := w.w.Seek(end, io.SeekStart)
+	return err
+}
+
+// Frames returns the number of sample frames written so far.
+func (w *WAVWriter) Frames() int {
+	return int(w.dataBytes / uint64(w.channels*w.bytesPerSample()))
+}
+
+// floatToInt16 converts a sample in [-1, 1] to 16-bit PCM, clamping
+// out-of-range values.
+func floatToInt16(v float32) int16 {
+	if v >= 1 {
+		return math.MaxInt16
+	}
+	if v <= -1 {
+		return -math.MaxInt16
+	}
+	return int16(math.Round(float64(v) * math.MaxInt16))
+}
+
+// WAVStems writes one mono WAV file per chip channel (tone 0-2, noise) from
+// the raw per-channel buffers, for mixing the channels separately.
+type WAVStems struct {
+	writers [4]*WAVWriter
+}
+
+// NewWAVStems creates one mono WAV writer per channel. ws[3] receives the
+// noise channel.
+func NewWAVStems(ws [4]io.WriteSeeker, sampleRate int, format WAVFormat) (*WAVStems, error) {
+	st := &WAVStems{}
+	for ch, w := range ws {
+		ww, err := NewWAVWriter(w, sampleRate, 1, format)
+		if err != nil {
+			return nil, err
+		}
+		st.writers[ch] = ww
+	}
+	return st, nil
+}
+
+// Writer returns the WAV writer for the given channel (0-3).
+func (st *WAVStems) Writer(ch int) *WAVWriter {
+	return st.writers[ch]
+}
+
+// WriteChannelBuffers writes the chip's raw per-channel output
+// (GetChannelBuffers) to the stems. No gain is applied.
+func (st *WAVStems) WriteChannelBuffers(chip *SN76489) error {
+	bufs, count := chip.GetChannelBuffers()
+	for ch, w := range st.writers {
+		if err := w.WriteSamples(bufs[ch][:count]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes all four stem writers, returning the first error.
+func (st *WAVStems) Close() error {
+	var first error
+	for _, w := range st.writers {
+		if err := w.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
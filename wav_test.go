@@ -0,0 +1,297 @@
+package sn76489
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"testing"
+)
+
+// memWriteSeeker is an in-memory io.WriteSeeker for WAV tests.
+type memWriteSeeker struct {
+	buf []byte
+	pos int
+}
+
+func (m *memWriteSeeker) Write(p []byte) (int, error) {
+	if end := m.pos + len(p); end > len(m.buf) {
+		m.buf = append(m.buf, make([]byte, end-len(m.buf))...)
+	}
+	n := copy(m.buf[m.pos:], p)
+	m.pos += n
+	return n, nil
+}
+
+func (m *memWriteSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		m.pos = int(offset)
+	case io.SeekCurrent:
+		m.pos += int(offset)
+	case io.SeekEnd:
+		m.pos = len(m.buf) + int(offset)
+	}
+	if m.pos < 0 {
+		return 0, errors.New("negative position")
+	}
+	return int64(m.pos), nil
+}
+
+// wavInfo holds the fields of a parsed WAV file.
+type wavInfo struct {
+	tag        uint16
+	channels   uint16
+	sampleRate uint32
+	bits       uint16
+	factFrames uint32
+	hasFact    bool
+	data       []byte
+}
+
+// parseWAV walks the RIFF chunks of a WAV file, checking the size fields.
+func parseWAV(t *testing.T, file []byte) wavInfo {
+	t.Helper()
+	if string(file[0:4]) != "RIFF" || string(file[8:12]) != "WAVE" {
+		t.Fatalf("Not a RIFF/WAVE file: % X", file[:12])
+	}
+	if got := binary.LittleEndian.Uint32(file[4:]); int(got) != len(file)-8 {
+		t.Errorf("RIFF size: expected %d, got %d", len(file)-8, got)
+	}
+
+	var info wavInfo
+	for pos := 12; pos+8 <= len(file); {
+		id := string(file[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(file[pos+4:]))
+		body := file[pos+8 : pos+8+size]
+		switch id {
+		case "fmt ":
+			info.tag = binary.LittleEndian.Uint16(body[0:])
+			info.channels = binary.LittleEndian.Uint16(body[2:])
+			info.sampleRate = binary.LittleEndian.Uint32(body[4:])
+			info.bits = binary.LittleEndian.Uint16(body[14:])
+		case "fact":
+			info.hasFact = true
+			info.factFrames = binary.LittleEndian.Uint32(body)
+		case "data":
+			info.data = body
+		}
+		pos += 8 + size + size&1
+	}
+	return info
+}
+
+// TestWAVWriter_Int16Mono verifies the PCM header and sample conversion,
+// including clamping.
+func TestWAVWriter_Int16Mono(t *testing.T) {
+	out := &memWriteSeeker{}
+	w, err := NewWAVWriter(out, 48000, 1, WAVInt16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples := []float32{0, 0.5, -0.5, 1, -1, 2, -2}
+	if err := w.WriteSamples(samples); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	info := parseWAV(t, out.buf)
+	if info.tag != wavTagPCM || info.channels != 1 || info.sampleRate != 48000 || info.bits != 16 {
+		t.Errorf("Bad fmt chunk: %+v", info)
+	}
+	if info.hasFact {
+		t.Error("PCM file should not have a fact chunk")
+	}
+	expected := []int16{0, 16384, -16384, 32767, -32767, 32767, -32767}
+	if len(info.data) != len(expected)*2 {
+		t.Fatalf("Data size: expected %d, got %d", len(expected)*2, len(info.data))
+	}
+	for i, e := range expected {
+		if got := int16(binary.LittleEndian.Uint16(info.data[i*2:])); got != e {
+			t.Errorf("Sample %d: expected %d, got %d", i, e, got)
+		}
+	}
+	if len(out.buf)%2 != 0 {
+		t.Error("Odd-length data chunk should be padded")
+	}
+}
+
+// TestWAVWriter_Offset verifies a WAV written after other data patches its
+// own header, not the start of the destination, and Close leaves the
+// destination just after the WAV.
+func TestWAVWriter_Offset(t *testing.T) {
+	out := &memWriteSeeker{}
+	prefix := []byte("CONTAINER HEADER")
+	out.Write(prefix)
+	w, err := NewWAVWriter(out, 48000, 1, WAVInt16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteSamples([]float32{0.5, -0.5, 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	out.Write([]byte("TRAILER"))
+
+	if string(out.buf[:len(prefix)]) != string(prefix) {
+		t.Fatalf("Prefix overwritten: %q", out.buf[:len(prefix)])
+	}
+	wavLen := len(out.buf) - len(prefix) - len("TRAILER")
+	if wavLen != 44+6 {
+		t.Fatalf("WAV length: expected %d, got %d", 44+6, wavLen)
+	}
+	if trailer := string(out.buf[len(prefix)+wavLen:]); trailer != "TRAILER" {
+		t.Errorf("Close should leave the position after the WAV, trailer at wrong place: %q", trailer)
+	}
+	info := parseWAV(t, out.buf[len(prefix):len(prefix)+wavLen])
+	if info.sampleRate != 48000 || len(info.data) != 6 {
+		t.Errorf("Bad embedded WAV: rate %d, %d data bytes", info.sampleRate, len(info.data))
+	}
+}
+
+// TestWAVWriter_Float32Stereo verifies the float header, fact chunk and
+// interleaving.
+func TestWAVWriter_Float32Stereo(t *testing.T) {
+	out := &memWriteSeeker{}
+	w, err := NewWAVWriter(out, 44100, 2, WAVFloat32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	left := []float32{0.1, 0.2, 0.3}
+	right := []float32{-0.1, -0.2, -0.3}
+	if err := w.WriteStereo(left, right); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if w.Frames() != 3 {
+		t.Errorf("Frames: expected 3, got %d", w.Frames())
+	}
+
+	info := parseWAV(t, out.buf)
+	if info.tag != wavTagFloat || info.channels != 2 || info.sampleRate != 44100 || info.bits != 32 {
+		t.Errorf("Bad fmt chunk: %+v", info)
+	}
+	if !info.hasFact || info.factFrames != 3 {
+		t.Errorf("Fact chunk: expected 3 frames, got %d (present=%v)", info.factFrames, info.hasFact)
+	}
+	for i := 0; i < 3; i++ {
+		l := math.Float32frombits(binary.LittleEndian.Uint32(info.data[i*8:]))
+		r := math.Float32frombits(binary.LittleEndian.Uint32(info.data[i*8+4:]))
+		if l != left[i] || r != right[i] {
+			t.Errorf("Frame %d: expected (%f, %f), got (%f, %f)", i, left[i], right[i], l, r)
+		}
+	}
+}
+
+// TestWAVWriter_WriteBuffer verifies chip output is streamed across frames
+// and matches GetBuffer.
+func TestWAVWriter_WriteBuffer(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	chip.Write(0x90)
+
+	out := &memWriteSeeker{}
+	w, err := NewWAVWriter(out, 48000, 1, WAVFloat32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected []float32
+	for frame := 0; frame < 3; frame++ {
+		chip.GenerateSamples(59659)
+		buf, count := chip.GetBuffer()
+		expected = append(expected, buf[:count]...)
+		if err := w.WriteBuffer(chip); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	info := parseWAV(t, out.buf)
+	if len(info.data) != len(expected)*4 {
+		t.Fatalf("Data size: expected %d, got %d", len(expected)*4, len(info.data))
+	}
+	for i, e := range expected {
+		if got := math.Float32frombits(binary.LittleEndian.Uint32(info.data[i*4:])); got != e {
+			t.Errorf("Sample %d: expected %f, got %f", i, e, got)
+			break
+		}
+	}
+}
+
+// TestWAVWriter_Errors verifies invalid arguments and writes after Close.
+func TestWAVWriter_Errors(t *testing.T) {
+	if _, err := NewWAVWriter(&memWriteSeeker{}, 48000, 0, WAVInt16); err == nil {
+		t.Error("NewWAVWriter should reject 0 channels")
+	}
+	if _, err := NewWAVWriter(&memWriteSeeker{}, 0, 1, WAVInt16); err == nil {
+		t.Error("NewWAVWriter should reject a 0 sample rate")
+	}
+
+	w, err := NewWAVWriter(&memWriteSeeker{}, 48000, 2, WAVInt16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteSamples([]float32{0, 0, 0}); err == nil {
+		t.Error("WriteSamples should reject a partial stereo frame")
+	}
+	if err := w.WriteStereo([]float32{0}, []float32{0, 0}); err == nil {
+		t.Error("WriteStereo should reject mismatched lengths")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteSamples([]float32{0, 0}); err == nil {
+		t.Error("WriteSamples should fail after Close")
+	}
+}
+
+// TestWAVStems verifies each stem receives its channel's raw buffer.
+func TestWAVStems(t *testing.T) {
+	chip := New(3579545, 48000, 800, Sega)
+	chip.Write(0x90) // Channel 0 max volume
+	chip.Write(0xF0) // Noise max volume
+	chip.Write(0xE4) // White noise
+
+	var outs [4]*memWriteSeeker
+	var ws [4]io.WriteSeeker
+	for ch := range outs {
+		outs[ch] = &memWriteSeeker{}
+		ws[ch] = outs[ch]
+	}
+	st, err := NewWAVStems(ws, 48000, WAVFloat32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chip.GenerateSamples(59659)
+	if err := st.WriteChannelBuffers(chip); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	bufs, count := chip.GetChannelBuffers()
+	for ch := 0; ch < 4; ch++ {
+		info := parseWAV(t, outs[ch].buf)
+		if info.channels != 1 {
+			t.Errorf("Stem %d: expected mono, got %d channels", ch, info.channels)
+		}
+		if len(info.data) != count*4 {
+			t.Fatalf("Stem %d: expected %d bytes, got %d", ch, count*4, len(info.data))
+		}
+		for i := 0; i < count; i++ {
+			if got := math.Float32frombits(binary.LittleEndian.Uint32(info.data[i*4:])); got != bufs[ch][i] {
+				t.Errorf("Stem %d sample %d: expected %f, got %f", ch, i, bufs[ch][i], got)
+				break
+			}
+		}
+	}
+}